@@ -0,0 +1,137 @@
+package components
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+//condition: <name> =|==|!=|in|not in <value>
+var conditionRegexp = regexp.MustCompile(`^\s*([\w.-]+)\s*(==|!=|=|\sin\s|\snot in\s)\s*(.+?)\s*$`)
+var variableRegexp = regexp.MustCompile(`\$\{([\w.-]+)\}`)
+
+//Expand replaces ${name} or ${claims.name} with values
+func Expand(s string, values map[string]string) string {
+	ret, _ := expand(s, values)
+	return ret
+}
+
+//expand replaces variables, returns false if a variable has no value
+func expand(s string, values map[string]string) (string, bool) {
+	var complete = true
+	ret := variableRegexp.ReplaceAllStringFunc(s, func(v string) string {
+		name := strings.TrimPrefix(v[2:len(v)-1], "claims.")
+		if _, ok := values[name]; !ok {
+			complete = false
+		}
+		return values[name]
+	})
+	return ret, complete
+}
+
+//Permitted checks roles and claims conditions against claims.
+//user needs one of roles (claim role or roles, comma separated) and all conditions must be true
+func Permitted(roles []string, conditions []string, claims map[string]string) (bool, error) {
+	if len(roles) > 0 {
+		var has = false
+		for _, role := range roles {
+			if inList(claims["role"], role) || inList(claims["roles"], role) {
+				has = true
+				break
+			}
+		}
+		if has == false {
+			return false, nil
+		}
+	}
+	for _, cond := range conditions {
+		ok, err := evalCondition(cond, claims, claims)
+		if err != nil || ok == false {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+//parseCondition splits condition in name, operator and value
+func parseCondition(cond string) (string, string, string, error) {
+	m := conditionRegexp.FindStringSubmatch(cond)
+	if m == nil {
+		return "", "", "", errors.New("Invalid condition: " + cond)
+	}
+	return m[1], strings.TrimSpace(m[2]), m[3], nil
+}
+
+//evalCondition evaluates single condition on values. ${var} in condition value is replaced from vars
+func evalCondition(cond string, values map[string]string, vars map[string]string) (bool, error) {
+	name, op, value, err := parseCondition(cond)
+	if err != nil {
+		return false, err
+	}
+	actual, ok := values[name]
+	if !ok {
+		return false, nil
+	}
+	value, ok = expand(value, vars)
+	if !ok {
+		return false, nil
+	}
+	switch op {
+	case "=", "==":
+		return actual == value, nil
+	case "!=":
+		return actual != value, nil
+	case "in", "not in":
+		if !(strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]")) {
+			return false, errors.New("Invalid list in condition: " + cond)
+		}
+		var found = false
+		for _, v := range strings.Split(value[1:len(value)-1], ",") {
+			if inList(actual, strings.TrimSpace(v)) {
+				found = true
+				break
+			}
+		}
+		return found == (op == "in"), nil
+	}
+	return false, errors.New("Invalid operator in condition: " + cond)
+}
+
+//inList checks if value is in comma separated list
+func inList(list, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, v := range strings.Split(list, ",") {
+		if strings.TrimSpace(v) == value {
+			return true
+		}
+	}
+	return false
+}
+
+//authorize checks authentication, roles and claims for request.
+//writes 401 or 403 response and returns false when not allowed
+func authorize(w http.ResponseWriter, r *http.Request, auth bool, roles, conditions []string) bool {
+	if auth == false && len(roles) == 0 && len(conditions) == 0 {
+		return true
+	}
+	principal, err := Authenticate(r)
+	if err != nil {
+		challenge(w, r)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	ok, err := Permitted(roles, conditions, principal.Claims)
+	if err != nil {
+		log.Println("ERROR:", err)
+	}
+	if ok == false {
+		log.Println("Forbidden:", r.URL.Path)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package components
+
+import "testing"
+
+func TestPermitted(t *testing.T) {
+	claims := map[string]string{"role": "user", "roles": "editor, viewer", "klant": "k1", "level": "3"}
+	tests := []struct {
+		roles      []string
+		conditions []string
+		want       bool
+	}{
+		{nil, nil, true},
+		{[]string{"user"}, nil, true},
+		{[]string{"viewer"}, nil, true},
+		{[]string{"admin"}, nil, false},
+		{[]string{"admin", "editor"}, nil, true},
+		{nil, []string{"klant = k1"}, true},
+		{nil, []string{"klant == k2"}, false},
+		{nil, []string{"klant != k2"}, true},
+		{nil, []string{"level in [1, 2, 3]"}, true},
+		{nil, []string{"level not in [1, 2, 3]"}, false},
+		{nil, []string{"missing = x"}, false},
+		{[]string{"user"}, []string{"klant = k1", "level = 4"}, false},
+	}
+	for _, test := range tests {
+		ok, err := Permitted(test.roles, test.conditions, claims)
+		if err != nil {
+			t.Errorf("%v %v: %v", test.roles, test.conditions, err)
+		}
+		if ok != test.want {
+			t.Errorf("%v %v: got %v, want %v", test.roles, test.conditions, ok, test.want)
+		}
+	}
+}
+
+func TestEvalCondition(t *testing.T) {
+	values := map[string]string{"Klant": "k1", "Status": "open"}
+	vars := map[string]string{"klant": "k1", "other": "k2"}
+	tests := []struct {
+		cond string
+		want bool
+	}{
+		{"Klant = ${claims.klant}", true},
+		{"Klant = ${claims.other}", false},
+		{"Klant = ${claims.missing}", false},
+		{"Klant != ${klant}", false},
+		{"Status in [open, closed]", true},
+		{"Status not in [closed]", true},
+		{"Missing = k1", false},
+	}
+	for _, test := range tests {
+		ok, err := evalCondition(test.cond, values, vars)
+		if err != nil {
+			t.Errorf("%s: %v", test.cond, err)
+		}
+		if ok != test.want {
+			t.Errorf("%s: got %v, want %v", test.cond, ok, test.want)
+		}
+	}
+	for _, cond := range []string{"Klant", "Status in open"} {
+		if _, err := evalCondition(cond, values, vars); err == nil {
+			t.Errorf("%s: expected error", cond)
+		}
+	}
+}
@@ -1,15 +1,16 @@
 package components
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
-	"git.muysers.nl/jmu0/jwt"
 	"github.com/graphql-go/graphql"
 	"github.com/jmu0/dbAPI/api"
 	"github.com/jmu0/dbAPI/db"
@@ -24,16 +25,39 @@ type Route struct {
 	Route   string   `yaml:"route"`
 	Type    string   `yaml:"type"`
 	Auth    bool     `yaml:"auth"`
+	Roles   []string `yaml:"roles"`  //user needs one of roles
+	Claims  []string `yaml:"claims"` //conditions on jwt claims, ex: keys == name=${user}
 	Methods string   `yaml:"methods"`
 	SQL     string   `yaml:"sql"`
-	Tables  []string `yaml:"tables"`
+	Params  []Param  `yaml:"params"`
+	Tables  []string `yaml:"tables"` //graphql: tables in schema. query: tables cached data depends on, default: tables in sql
+	TTL     string   `yaml:"ttl"`    //query: cache data, ex: 30s
+
+	//rest routes
+	Where        string   `yaml:"where"`         //row filter, ex: Klant = ${claims.klant}
+	ReadColumns  []string `yaml:"read_columns"`  //columns that can be read, default all
+	ReadDeny     []string `yaml:"read_deny"`     //columns that can't be read
+	WriteColumns []string `yaml:"write_columns"` //columns that can be written, default all
+	WriteDeny    []string `yaml:"write_deny"`    //columns that can't be written
+	Key          []string `yaml:"key"`           //primary key columns, needed for POST/PUT on table with where
+
+	Source    string `yaml:"-"` //api.yml file(s) route was loaded from
+	Component string `yaml:"-"` //component of api.yml file
 }
 
-var routes map[string]*Route
+var routes map[string]*Route //routes of LoadRoutesYaml and AddAPIRoutes, App loads routes into its own map
 var apiURL = "/api"
 
 //LoadRoutesYaml loads routes from yaml file and adds to routes slice
 func LoadRoutesYaml(path string) error {
+	if routes == nil {
+		routes = make(map[string]*Route)
+	}
+	return loadRoutesYaml(routes, path)
+}
+
+//loadRoutesYaml loads routes from yaml file and adds them to routes
+func loadRoutesYaml(routes map[string]*Route, path string) error {
 	// log.Println("Loading routes from", path)
 	yml, err := ioutil.ReadFile(path)
 	if err != nil {
@@ -44,16 +68,29 @@ func LoadRoutesYaml(path string) error {
 	if err != nil {
 		return err
 	}
-	if routes == nil {
-		routes = make(map[string]*Route)
-	}
 	for _, rt := range rts {
+		rt.Source = path
+		if _, err := parseTTL(rt.TTL); err != nil {
+			return errors.New(path + ": ttl of route " + rt.Route + ": " + err.Error())
+		}
+		for _, p := range rt.Params {
+			if err := p.check(); err != nil {
+				return errors.New(path + ": route " + rt.Route + ": " + err.Error())
+			}
+		}
 		if rt.Type == "graphql" {
 			if _, ok := routes[rt.Route]; ok {
+				routes[rt.Route].Source += ", " + path
 				routes[rt.Route].Tables = append(routes[rt.Route].Tables, rt.Tables...)
 				if routes[rt.Route].Auth == false && rt.Auth == true {
 					routes[rt.Route].Auth = true
 				}
+				roles, err := mergeRoles(routes[rt.Route].Roles, rt.Roles)
+				if err != nil {
+					return errors.New(path + ": graphql route " + rt.Route + ": " + err.Error())
+				}
+				routes[rt.Route].Roles = roles
+				routes[rt.Route].Claims = append(routes[rt.Route].Claims, rt.Claims...)
 				// log.Println("DEBUG added tables to route", rt.Route, rt.Tables)
 			} else {
 				routes[rt.Route] = rt
@@ -66,8 +103,80 @@ func LoadRoutesYaml(path string) error {
 	return nil
 }
 
-//AddAPIRoutes creates handlers for routes
-func AddAPIRoutes(mx *http.ServeMux, conn db.Conn) {
+//checkRoutes checks sql of routes, routes with parameters or row filter need a connection with query parameter support
+func checkRoutes(routes map[string]*Route, conn db.Conn) error {
+	_, params := conn.(sqlConn)
+	for _, rt := range routes {
+		if rt.SQL != "" {
+			_, names, err := compileSQL(rt.SQL)
+			if err != nil {
+				return errors.New(rt.Source + ": route " + rt.Route + ": " + err.Error())
+			}
+			if len(names) > 0 && conn != nil && !params {
+				return errors.New(rt.Source + ": route " + rt.Route + ": connection does not support query parameters")
+			}
+		}
+		if rt.Type == "rest" && rt.Where != "" && conn != nil && !params {
+			return errors.New(rt.Source + ": rest route " + rt.Route + ": where needs connection with query parameter support")
+		}
+	}
+	return nil
+}
+
+//mergeRoles combines roles of graphql route loaded from multiple files, requirements of both files must hold:
+//users need a role in both lists. error when no role is in both
+func mergeRoles(a, b []string) ([]string, error) {
+	if len(a) == 0 {
+		return b, nil
+	}
+	if len(b) == 0 {
+		return a, nil
+	}
+	var roles []string
+	for _, role := range a {
+		for _, r := range b {
+			if role == r {
+				roles = append(roles, role)
+				break
+			}
+		}
+	}
+	if len(roles) == 0 {
+		return nil, errors.New("conflicting roles: [" + strings.Join(a, ", ") + "] and [" + strings.Join(b, ", ") + "]")
+	}
+	return roles, nil
+}
+
+//setRoutesComponent sets component for routes loaded from api.yml file
+func setRoutesComponent(routes map[string]*Route, source, component string) {
+	for _, rt := range routes {
+		if rt.Component == "" && strings.HasPrefix(rt.Source, source) {
+			rt.Component = component
+		}
+	}
+}
+
+//info returns route table info for api route
+func (r *Route) info(pattern string, methods []string, h func(w http.ResponseWriter, r *http.Request)) RouteInfo {
+	return RouteInfo{
+		Pattern:   pattern,
+		Methods:   methods,
+		Type:      "api",
+		Auth:      r.Auth || len(r.Roles) > 0 || len(r.Claims) > 0,
+		Roles:     r.Roles,
+		Component: r.Component,
+		Source:    r.Source,
+		Handler:   http.HandlerFunc(h),
+	}
+}
+
+//AddAPIRoutes creates handlers for routes loaded with LoadRoutesYaml
+func AddAPIRoutes(mx *Router, conn db.Conn) {
+	addAPIRoutes(routes, mx, conn)
+}
+
+//addAPIRoutes creates handlers for routes
+func addAPIRoutes(routes map[string]*Route, mx *Router, conn db.Conn) {
 	// log.Println("DEBUG Routes", routes)
 	// log.Println("DEBUG graphql tables", routes["graphql"].Tables)
 	for _, r := range routes {
@@ -75,20 +184,26 @@ func AddAPIRoutes(mx *http.ServeMux, conn db.Conn) {
 		switch r.Type {
 		case "query":
 			log.Println("Adding route for api: /api/"+r.Route+"/ ("+r.Type+")", "auth:", r.Auth)
-			mx.HandleFunc("/api/"+r.Route+"/", queryHandler(*r, conn))
+			mx.HandleRoute(r.info("/api/"+r.Route+"/", methodsQuery, queryHandler(*r, conn)))
 		case "rest":
 			log.Println("Adding route for api: /api/"+r.Route+"/ ("+r.Type+")", "auth:", r.Auth)
-			mx.HandleFunc("/api/"+r.Route+"/", restHandler(*r, conn))
+			mx.HandleRoute(r.info("/api/"+r.Route+"/", parseMethods(r.Methods), restHandler(*r, conn)))
 		case "graphql":
 			log.Println("Adding route for api: /api/"+r.Route+" ("+r.Type+")", "auth:", r.Auth)
-			schema, err := api.BuildSchema(api.BuildSchemaArgs{
-				Tables: r.Tables,
-				Conn:   conn,
-			})
+			var schema graphql.Schema
+			var err error
+			if conn != nil {
+				schema, err = api.BuildSchema(api.BuildSchemaArgs{
+					Tables: r.Tables,
+					Conn:   conn,
+				})
+			} else {
+				err = errors.New("no database connection")
+			}
 			if err != nil {
 				log.Println("GraphQL Schema error:", err)
 			}
-			mx.HandleFunc("/api/"+r.Route, graphQLhandler(*r, &schema))
+			mx.HandleRoute(r.info("/api/"+r.Route, methodsQuery, graphQLhandler(*r, &schema)))
 		default:
 			log.Println("ERROR unknown route type:", r.Type)
 		}
@@ -99,19 +214,27 @@ func AddAPIRoutes(mx *http.ServeMux, conn db.Conn) {
 //restHandler handler for rest api requests
 func restHandler(route Route, conn db.Conn) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		r = authenticateRequest(r)
 		var allow = false
 		if strings.Contains(strings.ToLower(route.Methods), strings.ToLower(r.Method)) {
 			allow = true
 		}
-		if route.Auth == true {
-			if jwt.Authenticated(r) == false {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
-			}
+		if authorize(w, r, route.Auth, route.Roles, route.Claims) == false {
+			return
 		}
 		if allow == true {
-			api.RestHandler(apiURL, conn)(w, r)
-			// api.HandleREST(apiURL, w, r)
+			sw := &statusWriter{ResponseWriter: w}
+			if route.restricted() {
+				restFiltered(route, conn, sw, r)
+			} else {
+				api.RestHandler(apiURL, conn)(sw, r)
+				// api.HandleREST(apiURL, w, r)
+			}
+			if r.Method != http.MethodGet && r.Method != http.MethodHead && sw.status < 400 {
+				if table := restTable(r.URL.Path); table != "" {
+					requestCache(r.Context()).Invalidate(table)
+				}
+			}
 		} else {
 			log.Println("Method not allowed:", r.Method, r.URL.Path)
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -123,15 +246,17 @@ func restHandler(route Route, conn db.Conn) func(w http.ResponseWriter, r *http.
 //queryHandler creates handler func for query route
 func queryHandler(route Route, conn db.Conn) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if route.Auth == true {
-			if jwt.Authenticated(r) == false {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
-			}
+		r = authenticateRequest(r)
+		if authorize(w, r, route.Auth, route.Roles, route.Claims) == false {
+			return
 		}
 
-		data, err := route.GetData(r.URL.Path, conn)
-		if err != nil {
+		data, err := route.GetRequestData(r, conn)
+		if perr, ok := err.(*ParamError); ok {
+			log.Println("Error handle data:", err)
+			http.Error(w, perr.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
 			log.Println("Error handle data:", err)
 			http.NotFound(w, r)
 			return
@@ -143,63 +268,172 @@ func queryHandler(route Route, conn db.Conn) func(w http.ResponseWriter, r *http
 			return
 		}
 		log.Println("Serving data:", r.URL.Path)
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		w.Write(bytes)
+		varyAuth(w, r)
+		writeResponse(w, r, "application/json; charset=utf-8", bytes, "", time.Time{})
 	}
 }
 
 func graphQLhandler(route Route, schema *graphql.Schema) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if route.Auth == true {
-			if jwt.Authenticated(r) == false {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
-			}
+		r = authenticateRequest(r)
+		if authorize(w, r, route.Auth, route.Roles, route.Claims) == false {
+			return
 		}
 		api.HandleGQL(schema, w, r)
 	}
 }
 
-//GetData gets data. keys from url path
+//GetData gets data. keys from url path are passed to the query as bind parameters
 func (r *Route) GetData(path string, conn db.Conn) ([]map[string]interface{}, error) {
+	return r.getData(context.Background(), path, make(map[string]string), conn)
+}
+
+//GetRequestData gets data. parameters from url path, query string, jwt claims or json body
+func (r *Route) GetRequestData(req *http.Request, conn db.Conn) ([]map[string]interface{}, error) {
+	values, err := requestValues(r.Params, req)
+	if err != nil {
+		return make([]map[string]interface{}, 0), err
+	}
+	return r.getData(req.Context(), req.URL.Path, values, conn)
+}
+
+func (r *Route) getData(ctx context.Context, path string, values map[string]string, conn db.Conn) ([]map[string]interface{}, error) {
 	var ret = make([]map[string]interface{}, 0)
-	var query, param string
-	params := make([]interface{}, 0)
 	if r.SQL == "" {
 		return ret, nil
 	}
-	if path[len(path)-1:] != "/" {
+	keys := make([]string, 0)
+	if len(path) > 0 && path[len(path)-1:] != "/" {
 		spl := strings.Split(path, "/")
-		keys := strings.Split(spl[len(spl)-1], ":")
-		for i := range keys {
-			param = db.Escape(strings.TrimSpace(keys[i]))
-			if len(param) > 0 {
-				params = append(params, param)
+		for _, key := range strings.Split(spl[len(spl)-1], ":") {
+			key = strings.TrimSpace(key)
+			if len(key) > 0 {
+				keys = append(keys, key)
 			}
 		}
 	}
-	if len(params) == 0 {
-		query = r.SQL
-	} else {
-		query = fmt.Sprintf(r.SQL, params...)
+	query, names, err := compileSQL(r.SQL)
+	if err != nil {
+		return ret, err
+	}
+	for k, v := range r.pathValues(names, keys) {
+		if _, ok := values[k]; !ok {
+			values[k] = v
+		}
+	}
+	args, err := r.bindArgs(names, values)
+	if err != nil {
+		return ret, err
 	}
 	if strings.ToLower(strings.TrimSpace(query)[:6]) == "select" {
-		res, err := conn.Query(query)
+		ttl, _ := parseTTL(r.TTL)
+		key := queryCacheKey(query, args)
+		if ttl > 0 {
+			if data, ok := requestCache(ctx).Get(key); ok {
+				return data, nil
+			}
+		}
+		res, err := queryArgs(ctx, conn, query, args)
 		if err != nil {
 			return ret, err
 		}
 		if len(res) == 0 {
 			return ret, errors.New("Data not found: " + path)
 		}
+		if ttl > 0 {
+			tables := r.Tables
+			if len(tables) == 0 {
+				tables = queryTables(query)
+			}
+			requestCache(ctx).Set(key, res, ttl, tables)
+		}
 		ret = res
 	} else {
-		id, rows, err := conn.Execute(query)
+		id, rows, err := executeArgs(ctx, conn, query, args)
 		if err != nil {
 			return ret, err
 		}
+		for _, table := range queryTables(query) {
+			requestCache(ctx).Invalidate(table)
+		}
 		ret = append(ret, make(map[string]interface{}))
 		ret[0]["id"] = id
 		ret[0]["n"] = rows
 	}
 	return ret, nil
 }
+
+//paramName returns the name of the parameter at position i in the query
+func (r *Route) paramName(names []string, i int) string {
+	if names[i] != "" {
+		return names[i]
+	}
+	if i < len(r.Params) && r.Params[i].Name != "" {
+		return r.Params[i].Name
+	}
+	return strconv.Itoa(i + 1)
+}
+
+//param returns declared parameter for name. undeclared parameters are required path strings
+func (r *Route) param(name string) Param {
+	for _, p := range r.Params {
+		if p.Name == name {
+			return p
+		}
+	}
+	return Param{Name: name, Required: true}
+}
+
+//pathValues maps keys from url path to parameter names, in order of declaration or appearance
+func (r *Route) pathValues(names []string, keys []string) map[string]string {
+	var values = make(map[string]string)
+	var order = make([]string, 0)
+	if len(r.Params) > 0 {
+		for _, p := range r.Params {
+			if p.Source == "" || strings.ToLower(p.Source) == "path" {
+				order = append(order, p.Name)
+			}
+		}
+	} else {
+		var seen = make(map[string]bool)
+		for i := range names {
+			name := r.paramName(names, i)
+			if !seen[name] {
+				seen[name] = true
+				order = append(order, name)
+			}
+		}
+	}
+	for i, name := range order {
+		if i < len(keys) {
+			values[name] = keys[i]
+		}
+	}
+	return values
+}
+
+//bindArgs builds typed query arguments for parameter names
+func (r *Route) bindArgs(names []string, values map[string]string) ([]interface{}, error) {
+	var args = make([]interface{}, 0)
+	for i := range names {
+		name := r.paramName(names, i)
+		p := r.param(name)
+		value, ok := values[name]
+		if !ok && p.Default != "" {
+			value, ok = p.Default, true
+		}
+		if !ok {
+			if p.Required {
+				return nil, &ParamError{Param: name, Msg: "missing"}
+			}
+			args = append(args, nil)
+			continue
+		}
+		arg, err := p.Coerce(value)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
@@ -3,8 +3,10 @@ package components
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/json"
 	"errors"
+	"html"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -12,11 +14,11 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jmu0/dbAPI/db"
 
-	"git.muysers.nl/jmu0/jwt"
 	"github.com/jmu0/settings"
 	"github.com/jmu0/templates"
 	"github.com/tdewolff/minify"
@@ -26,8 +28,6 @@ import (
 //DataFunc function for getting data for component
 type DataFunc func(args map[string]string, keys []string, conn db.Conn) ([]map[string]interface{}, error)
 
-var templateCache []byte
-
 //App struct for app data
 type App struct {
 	Title           string   `json:"title" yaml:"title"`
@@ -38,64 +38,200 @@ type App struct {
 	Scripts         []string `json:"scripts" yaml:"scripts"`
 	Debug           bool     `json:"debug" yaml:"debug"`
 	ConfigFile      string
-	Mux             *http.ServeMux
+	Mux             *http.ServeMux //optional, app is added as handler for /
+	Router          *Router        //routes of app, rebuilt on reload
 	Components      map[string]Component
 	Pages           []Page
 	TemplateManager templates.TemplateManager
-	JsCache         []byte
+	JsCache         Encoded                //script bundle, identity, gzip and brotli
+	JsURL           string                 //content hashed url of script bundle
+	JsSourceMap     []byte                 //source map of script bundle
+	JsChunks        map[string]ScriptChunk //script bundle per component (split_scripts)
+	CSSCache        Encoded
+	CSSURL          string //content hashed url of stylesheet: MainCSSFile and .css files of components
 	Port            string `json:"port" yaml:"port"`
 	StartTime       time.Time
 	RootPath        string
 	Conn            db.Conn
 	DataFuncs       map[string]DataFunc
-	MainSassFile    string `json:"main-sass-file" yaml:"main-sass-file"`
-	MainCSSFile     string `json:"main-css-file" yaml:"main-css-file"`
-	Webpack         bool   `json:"webpack" yaml:"webpack"`
+	DataFuncsCtx    map[string]DataFuncCtx
+	MainSassFile    string            `json:"main-sass-file" yaml:"main-sass-file"`
+	MainCSSFile     string            `json:"main-css-file" yaml:"main-css-file"`
+	Webpack         bool              `json:"webpack" yaml:"webpack"`
+	Forbidden       string            `json:"forbidden" yaml:"forbidden"`               //component rendered when user is not permitted, default: forbidden
+	Authenticator   Authenticator     `json:"-" yaml:"-"`                               //default: JWTAuthenticator
+	AuthFunc        AuthFunc          `json:"-" yaml:"-"`                               //checks username and password for /auth/login
+	AuthSecret      []byte            `json:"-" yaml:"-"`                               //secret for signing jwt tokens
+	AuthLifetime    string            `json:"auth_lifetime" yaml:"auth_lifetime"`       //token or session lifetime, ex: 8h
+	AuthCookie      bool              `json:"auth_cookie" yaml:"auth_cookie"`           //use HttpOnly cookie for token or session
+	Login           string            `json:"login" yaml:"login"`                       //login component, default: login
+	Locales         []string          `json:"locales" yaml:"locales"`                   //localized routes for locales, default: nl, de, en
+	DefaultLocale   string            `json:"default_locale" yaml:"default_locale"`     //default: nl
+	NotFound        string            `json:"not_found" yaml:"not_found"`               //component rendered for unknown paths, default: not_found
+	Error           string            `json:"error" yaml:"error"`                       //component rendered on errors, default: error
+	DataWorkers     int               `json:"data_workers" yaml:"data_workers"`         //concurrent data calls per page, default: 4
+	PageTimeout     string            `json:"page_timeout" yaml:"page_timeout"`         //deadline for data of pages without timeout, ex: 10s
+	SourceMap       bool              `json:"source_map" yaml:"source_map"`             //generate source map for script bundle
+	SplitScripts    bool              `json:"split_scripts" yaml:"split_scripts"`       //core bundle with app scripts, pages load chunks of their components
+	ScopeStyles     bool              `json:"scope_styles" yaml:"scope_styles"`         //prefix selectors in .css files of components with component tag
+	SourceMapPath   string            `json:"source_map_path" yaml:"source_map_path"`   //url of source map, default: <script bundle url>.map
+	SourceMapAuth   bool              `json:"source_map_auth" yaml:"source_map_auth"`   //serve source map to authenticated users only
+	SourceMapRoles  []string          `json:"source_map_roles" yaml:"source_map_roles"` //serve source map to users with one of roles
+	Cache           Cache             `json:"-" yaml:"-"`                               //cache for data with ttl, default: LRUCache
+	CacheSize       int               `json:"cache_size" yaml:"cache_size"`             //entries in default cache, default: 1000
+	base            *App              //app as configured by caller, copied on every (re)load
+	settings        *appSettings      //settings of load for requests served by app
+	routes          map[string]*Route //api routes loaded from api.yml files
 }
 
-//Init initializes the app
+//Init initializes the app. app handles / on Mux, in debug mode app is reloaded when files change
 func (a *App) Init() error {
-	err := a.LoadConfig()
+	base := *a
+	a.base = &base
+	reloadMutex.Lock()
+	next, err := a.load()
+	if err == nil {
+		a.swap(next)
+	}
+	reloadMutex.Unlock()
 	if err != nil {
 		return err
 	}
-	if a.Port == "" {
-		a.Port = ":8080"
+	if a.Mux == nil {
+		a.Mux = http.NewServeMux()
+	}
+	a.Mux.Handle("/", a)
+	if a.Debug == true {
+		go a.watch()
 	}
-	err = a.LoadComponents()
+	return nil
+}
+
+//load loads config, components, routes and layouts into a new app with its own Router. a is not modified
+func (a *App) load() (*App, error) {
+	next := *a.base
+	next.base = a.base
+	next.Router = NewRouter()
+	if a.Authenticator != nil {
+		next.Authenticator = a.Authenticator //keep sessions on reload
+	}
+	if a.Cache != nil {
+		next.Cache = a.Cache //keys include sql, changed queries don't use old data
+	}
+	err := next.LoadConfig()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if next.Port == "" {
+		next.Port = ":8080"
 	}
-	err = a.AddRoutes(a.Conn)
+	if next.Authenticator == nil {
+		var lifetime time.Duration
+		if next.AuthLifetime != "" {
+			lifetime, err = time.ParseDuration(next.AuthLifetime)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(next.AuthSecret) > 0 {
+			var cookie string
+			if next.AuthCookie == true {
+				cookie = "token"
+			}
+			next.Authenticator = &JWTAuthenticator{Secret: next.AuthSecret, Lifetime: lifetime, Cookie: cookie}
+		} else if next.AuthCookie == true {
+			next.Authenticator = &SessionAuthenticator{Lifetime: lifetime}
+		} else if next.AuthFunc != nil && next.AuthLifetime != "" {
+			//tokens of jwt package ignore lifetime, uri and refresh
+			return nil, errors.New("auth_lifetime needs App.AuthSecret or auth_cookie")
+		} else {
+			next.Authenticator = &JWTAuthenticator{}
+		}
+	}
+	if next.Cache == nil {
+		next.Cache = NewLRUCache(next.CacheSize)
+	}
+	err = next.LoadComponents()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if a.TemplateManager.Cache == nil {
-		a.TemplateManager.Cache = make(map[string]*templates.Template)
+	err = checkRoutes(next.routes, next.Conn)
+	if err != nil {
+		return nil, err
+	}
+	def, list := defaultLocales()
+	if next.DefaultLocale != "" {
+		def = next.DefaultLocale
+	}
+	if len(next.Locales) > 0 {
+		list = next.Locales
+	}
+	//translations fall back to default locale of app
+	fillTranslations(next.TemplateManager.LocalizationData, def, list)
+	//hashed urls are needed in layouts
+	if next.Debug == false {
+		next.LoadScriptCache()
+	}
+	next.LoadStyleCache()
+	//layouts are added to copy, cache of base app is used by the app that is serving
+	layouts := make(map[string]*templates.Template, len(next.TemplateManager.Cache))
+	for name, tmpl := range next.TemplateManager.Cache {
+		layouts[name] = tmpl
 	}
-	var main = templates.Template{}
-	main.Data = make(map[string]interface{})
-	err = main.Load(a.RootPath + a.ComponentsPath + "/" + a.MainPath)
+	next.TemplateManager.Cache = layouts
+	main, err := next.loadLayout(next.MainPath)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	next.TemplateManager.Cache["main"] = main
+	for _, page := range next.Pages {
+		if page.Layout != "" && page.Layout != next.MainPath {
+			if _, ok := next.TemplateManager.Cache[page.Layout]; !ok {
+				layout, err := next.loadLayout(page.Layout)
+				if err != nil {
+					return nil, err
+				}
+				next.TemplateManager.Cache[page.Layout] = layout
+			}
+		}
 	}
-	main.Data["scripts"] = a.ScriptTags() //strings.Join(a.ScriptTags(), "\n")
-	main.Data["templates"] = a.TemplateTags()
-	main.Data["title"] = a.Title
+	next.settings = &appSettings{authenticator: next.Authenticator, cache: next.Cache, defaultLocale: def, locales: list}
+	next.StartTime = time.Now()
+	err = next.AddRoutes(next.Conn)
+	if err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
+
+//loadLayout loads layout html file from components path
+func (a *App) loadLayout(path string) (*templates.Template, error) {
+	var layout = templates.Template{}
+	layout.Data = make(map[string]interface{})
+	err := layout.Load(a.RootPath + a.ComponentsPath + "/" + path)
+	if err != nil {
+		return nil, err
+	}
+	layout.HTML = a.hashStyleURL(layout.HTML)
+	layout.Data["scripts"] = a.ScriptTags() //strings.Join(a.ScriptTags(), "\n")
+	layout.Data["templates"] = a.TemplateTags()
+	layout.Data["title"] = a.Title
+	layout.Data["description"] = ""
+	layout.Data["canonical"] = ""
+	layout.Data["meta"] = ""
 	if a.Debug == true {
-		main.Data["debug"] = "true"
+		layout.Data["debug"] = "true"
 	} else {
-		main.Data["debug"] = "false"
+		layout.Data["debug"] = "false"
 	}
 	if len(a.TemplateManager.LocalizationData) > 0 {
 		bytes, err := json.Marshal(a.TemplateManager.LocalizationData)
 		if err == nil {
-			main.Data["localizationJSON"] = string(bytes)
+			layout.Data["localizationJSON"] = string(bytes)
 		}
 	}
-	a.TemplateManager.Cache["main"] = &main
-	a.StartTime = time.Now()
-	return nil
+	layout.Data["chunksJSON"] = a.chunksJSON()
+	return &layout, nil
 }
 
 //LoadConfig loads json config file
@@ -110,12 +246,38 @@ func (a *App) LoadConfig() error {
 	if a.MainSassFile == "" {
 		a.MainSassFile = "static/css/main.scss"
 	}
+	if a.Forbidden == "" {
+		a.Forbidden = "forbidden"
+	}
+	if a.Login == "" {
+		a.Login = "login"
+	}
+	if a.DefaultLocale == "" {
+		a.DefaultLocale = "nl"
+	}
+	if len(a.Locales) == 0 {
+		a.Locales = []string{a.DefaultLocale, "de", "en"}
+	}
+	if isLocaleIn(a.DefaultLocale, a.Locales) == false {
+		a.Locales = append([]string{a.DefaultLocale}, a.Locales...)
+	}
+	if a.NotFound == "" {
+		a.NotFound = "not_found"
+	}
+	if a.Error == "" {
+		a.Error = "error"
+	}
+	if a.DataWorkers < 1 {
+		a.DataWorkers = 4
+	}
 	return nil
 }
 
 //LoadComponents loads components from path
 func (a *App) LoadComponents() error {
 	a.Components = make(map[string]Component)
+	a.routes = make(map[string]*Route)
+	a.TemplateManager.LocalizationData = copyLocalization(a.TemplateManager.LocalizationData)
 	var paths []string
 	if a.ComponentPaths != nil {
 		paths = a.ComponentPaths
@@ -123,6 +285,11 @@ func (a *App) LoadComponents() error {
 		paths = append(paths, a.ComponentsPath)
 	}
 	for _, path := range paths {
+		//app translations without namespace
+		err := a.loadTranslations(Component{Path: a.RootPath + path})
+		if err != nil {
+			return err
+		}
 		files, err := ioutil.ReadDir(a.RootPath + path)
 		if err != nil {
 			return err
@@ -139,6 +306,15 @@ func (a *App) LoadComponents() error {
 			a.loadComponentFolder(a.RootPath + path)
 		}
 	}
+	err := a.resolveAPIData()
+	if err != nil {
+		return err
+	}
+	if a.Debug == true {
+		for _, key := range missingTranslations(a.TemplateManager.LocalizationData, a.Locales) {
+			log.Println("Missing translation:", key)
+		}
+	}
 	return nil
 }
 
@@ -172,8 +348,14 @@ func (a *App) loadComponentFolder(path string) error {
 				c.Name = c.Name[1:]
 			}
 			c.Name = strings.Replace(c.Name, "/", ".", -1)
-			if f, ok := a.DataFuncs[c.Name]; ok {
-				c.DataFunc = f
+			c.DataFuncCtx, err = a.dataFunc(c)
+			if err != nil {
+				return err
+			}
+			setRoutesComponent(a.routes, path+"/api.yml", c.Name)
+			err = a.loadTranslations(c)
+			if err != nil {
+				return err
 			}
 			a.Components[c.Name] = c
 			log.Println("Loading component:", c.Name, "from", c.Path)
@@ -203,7 +385,7 @@ func (a *App) loadComponent(path string) (Component, error) {
 		Path: path,
 	}
 	if _, err := os.Stat(path + "/api.yml"); err == nil {
-		err = LoadRoutesYaml(path + "/api.yml")
+		err = loadRoutesYaml(a.routes, path+"/api.yml")
 		if err != nil {
 			return c, err
 		}
@@ -217,50 +399,154 @@ func (a *App) loadComponent(path string) (Component, error) {
 	if len(stylefiles) > 0 && err == nil {
 		c.StyleFiles = append(c.StyleFiles, stylefiles...)
 	}
+	stylefiles, err = filepath.Glob(c.Path + "/*.css")
+	if len(stylefiles) > 0 && err == nil {
+		c.StyleFiles = append(c.StyleFiles, stylefiles...)
+	}
 	jsfiles, err := filepath.Glob(c.Path + "/*.js")
 	if len(jsfiles) > 0 && err == nil {
 		c.JsFiles = jsfiles
 	}
+	c.Data, err = loadDataSource(path)
+	if err != nil {
+		return c, err
+	}
 	c.TemplateManager = templates.TemplateManager{}
 	c.TemplateManager.Preload(path)
 	c.TemplateManager.LocalizationData = a.TemplateManager.LocalizationData
 	return c, nil
 }
 
-func (a *App) handleFunc(page Page) func(w http.ResponseWriter, r *http.Request) {
+//pageRoute pages registered on a router pattern
+type pageRoute struct {
+	page     *Page          //page without path parameters, nil if none
+	locale   string         //locale for localized route
+	patterns []routePattern //pages with path parameters
+	exact    bool           //page only matches pattern itself (root page)
+}
+
+//handlePageRoute serves first page matching path, 404 when no page matches
+func (a *App) handlePageRoute(pr *pageRoute) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		args := GetRequestArgs(r)
-		if page.Auth == true {
-			if jwt.Authenticated(r) == false {
-				//render login component, if it exists
-				if login, ok := a.Components["login"]; ok {
-					data := make(map[string]interface{})
-					data["error"] = r.Header.Get("error")
-					data["uri"] = r.URL.Path
-					log.Println("Rendering login")
-					html, err := login.Render("", args, data)
-					if err == nil {
-						w.Write([]byte(html))
-						return
-					}
+		for _, pattern := range pr.patterns {
+			if params, typed, ok := pattern.match(r.URL.Path); ok {
+				if pattern.locale != "" {
+					r = withLocale(r, pattern.locale)
 				}
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				a.servePage(w, r, pattern.page, params, typed)
 				return
 			}
 		}
-		log.Println("Rendering", page.Route)
-		content, err := page.Render(args, a.Components, a.Conn)
+		if pr.page != nil && pr.matchPage(r.URL.Path) {
+			if pr.locale != "" {
+				r = withLocale(r, pr.locale)
+			}
+			a.servePage(w, r, *pr.page, nil, nil)
+			return
+		}
+		a.renderNotFound(w, r)
+	}
+}
+
+//servePage renders page with path parameters. data funcs get request context with page timeout
+func (a *App) servePage(w http.ResponseWriter, r *http.Request, page Page, params map[string]string, typed map[string]interface{}) {
+	r = authenticateRequest(r)
+	if page.Timeout == "" {
+		page.Timeout = a.PageTimeout
+	}
+	if page.Timeout != "" {
+		timeout, err := time.ParseDuration(page.Timeout)
+		if err != nil {
+			log.Println("ERROR page timeout:", page.Route, err)
+		} else {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+	}
+	rememberLocale(w, r)
+	rc := NewRenderContext(r)
+	rc.SetParams(params)
+	rc.TypedParams = typed
+	rc.loader = newDataLoader(a.DataWorkers)
+	if page.Auth == true || len(page.Roles) > 0 || len(page.Claims) > 0 {
+		principal, err := Authenticate(r)
+		if err != nil {
+			//render login component, if it exists
+			if a.renderLogin(w, r, http.StatusOK, r.Header.Get("error"), r.URL.Path) {
+				return
+			}
+			challenge(w, r)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		ok, err := Permitted(page.Roles, page.Claims, principal.Claims)
 		if err != nil {
 			log.Println("ERROR:", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		if ok == false {
+			//render forbidden component, if it exists
+			data := make(map[string]interface{})
+			data["uri"] = html.EscapeString(r.URL.Path)
+			a.renderStatus(w, r, http.StatusForbidden, a.Forbidden, data)
 			return
 		}
+	}
+	log.Println("Rendering", page.Route)
+	defer func() {
+		if rec := recover(); rec != nil {
+			a.renderError(w, r, recovered("panic", rec))
+		}
+	}()
+	content, err := page.RenderWith(rc, a.Components, a.Conn)
+	if r.Context().Err() == context.Canceled {
+		log.Println("Request cancelled:", r.URL.Path)
+		return
+	}
+	if err == context.DeadlineExceeded {
+		a.renderErrorStatus(w, r, http.StatusGatewayTimeout, errors.New("Timeout loading data of page "+page.Route))
+		return
+	}
+	if err != nil {
+		a.renderError(w, r, err)
+		return
+	}
+
+	rc.Content = content
+	rc.Components = page.componentNames()
+	rc.SetPage(page, &a.TemplateManager)
+	html, err := a.renderMain(rc, page.Layout)
+	if err != nil {
+		a.renderError(w, r, err)
+		return
+	}
+	varyLocale(w)
+	varyAuth(w, r)
+	writeResponse(w, r, "text/html; charset=utf-8", []byte(html), "", time.Time{})
+}
 
-		a.TemplateManager.Cache["main"].Data["content"] = content
-		html, err := a.TemplateManager.Render(a.TemplateManager.Cache["main"], args["locale"])
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		w.Write([]byte(html))
+//pageInfo returns auth, roles and component names of page for route table
+func pageInfo(page Page) (bool, []string, string) {
+	names := make([]string, 0, len(page.Components))
+	for _, part := range page.Components {
+		names = append(names, part.Name)
 	}
+	return page.Auth || len(page.Roles) > 0 || len(page.Claims) > 0, page.Roles, strings.Join(names, ",")
+}
+
+//renderMain renders layout (default: main template) with request data
+func (a *App) renderMain(rc *RenderContext, layout string) (string, error) {
+	main, ok := a.TemplateManager.Cache[layout]
+	if layout == "" || !ok {
+		main = a.TemplateManager.Cache["main"]
+	}
+	data := rc.Data(main.Data)
+	a.addScriptChunks(data, rc.Components)
+	html, err := render(&a.TemplateManager, main, data, rc.Locale)
+	if err != nil {
+		return html, err
+	}
+	return translateMarkers(&a.TemplateManager, html, "", rc.Locale), nil
 }
 
 //AddRoutes adds routes for app
@@ -268,77 +554,156 @@ func (a *App) AddRoutes(conn db.Conn) error {
 	//Add route for static path
 	if a.StaticPath != "" {
 		log.Println("Adding route for: favicon.ico")
-		a.Mux.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) {
+		a.Router.HandleFunc("/favicon.ico", methodsGet, "static", func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Cache-control", "max-age=86400")
 			http.FileServer(http.Dir(a.RootPath+a.StaticPath)).ServeHTTP(w, r)
 		})
 		log.Println("Adding route for:", a.StaticPath)
-		a.Mux.HandleFunc("/"+a.StaticPath+"/", func(w http.ResponseWriter, r *http.Request) {
+		a.Router.HandleRoute(RouteInfo{Pattern: "/" + a.StaticPath + "/", Methods: methodsGet, Type: "static", Source: a.RootPath + a.StaticPath, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			log.Println("Serving:", r.URL.Path)
 			w.Header().Set("Cache-control", "max-age=90")
 			if a.RootPath == "" {
-				http.FileServer(http.Dir("./")).ServeHTTP(w, r)
+				serveStatic("./").ServeHTTP(w, r)
 			} else {
-				http.FileServer(http.Dir(a.RootPath)).ServeHTTP(w, r)
+				serveStatic(a.RootPath).ServeHTTP(w, r)
 			}
-		})
+		})})
+	}
+
+	//Add routes for authentication
+	if a.AuthFunc != nil {
+		log.Println("Adding routes for authentication: /auth/login, /auth/logout, /auth/refresh")
+		a.Router.HandleFunc("/auth/login", methodsPost, "auth", a.handleLogin)
+		a.Router.HandleFunc("/auth/logout", methodsPost, "auth", a.handleLogout)
+		a.Router.HandleFunc("/auth/refresh", methodsPost, "auth", a.handleRefresh)
 	}
 
 	//Add routes for Pages
+	var pageRoutes = make(map[string]*pageRoute)
+	var prefixes = make([]string, 0)
+	addPageRoute := func(prefix string, page Page, locale string) {
+		if _, ok := pageRoutes[prefix]; !ok {
+			pageRoutes[prefix] = &pageRoute{}
+			prefixes = append(prefixes, prefix)
+		}
+		if hasParams(page.Route) {
+			pattern := parsePattern(page)
+			pattern.locale = locale
+			pageRoutes[prefix].patterns = append(pageRoutes[prefix].patterns, pattern)
+		} else if pageRoutes[prefix].page == nil {
+			pageRoutes[prefix].page = &page
+			pageRoutes[prefix].locale = locale
+		}
+	}
 	for _, page := range a.Pages {
 		if len(page.Route) == 0 {
 			return errors.New("No route given for page, check config")
 		}
-		if page.Route[len(page.Route)-1] != '/' {
+		if hasParams(page.Route) == false && page.Route[len(page.Route)-1] != '/' {
 			page.Route += "/"
 		}
-		log.Println("Adding route for page:", page.Route)
-		a.Mux.HandleFunc(page.Route, a.handleFunc(page))
+		var routes = []string{page.Route}
+		var routeLocales = []string{""}
 		if len(page.Route) > 1 {
-			deRoute := a.TemplateManager.Translate(strings.Replace(page.Route, "/", "", -1), "de")
-			if deRoute != strings.Replace(page.Route, "/", "", -1) {
-				deRoute = "/" + strings.Replace(deRoute, " ", "", -1) + "/"
-				log.Println("Adding route for page:", deRoute)
-				a.Mux.HandleFunc(deRoute, a.handleFunc(page))
+			//localized routes
+			for _, locale := range a.Locales {
+				route := a.localizeRoute(page.Route, locale)
+				if locale != a.DefaultLocale && route != page.Route {
+					routes = append(routes, route)
+					routeLocales = append(routeLocales, locale)
+				}
 			}
-			enRoute := a.TemplateManager.Translate(strings.Replace(page.Route, "/", "", -1), "en")
-			if enRoute != strings.Replace(page.Route, "/", "", -1) {
-				enRoute = "/" + strings.Replace(enRoute, " ", "", -1) + "/"
-				log.Println("Adding route for page:", enRoute)
-				a.Mux.HandleFunc(enRoute, a.handleFunc(page))
+		}
+		for i, route := range routes {
+			localized := page
+			localized.Route = route
+			if hasParams(route) {
+				addPageRoute(parsePattern(localized).prefix(), localized, routeLocales[i])
+			} else {
+				addPageRoute(route, localized, routeLocales[i])
 			}
 		}
+		if page.Route == "/" {
+			pageRoutes["/"].exact = true
+		}
+	}
+	if _, ok := pageRoutes["/"]; !ok {
+		//not found for unknown paths
+		pageRoutes["/"] = &pageRoute{}
+		prefixes = append(prefixes, "/")
+	}
+	//Add routes for locale prefix: /<locale>/...
+	for _, locale := range a.Locales {
+		if _, ok := pageRoutes["/"+locale+"/"]; !ok {
+			log.Println("Adding route for locale: /" + locale + "/")
+			a.Router.HandleFunc("/"+locale+"/", nil, "locale", a.handleLocalePrefix(locale))
+		}
+	}
+	for _, prefix := range prefixes {
+		log.Println("Adding route for page:", prefix)
+		pr := pageRoutes[prefix]
+		info := RouteInfo{Pattern: prefix, Methods: methodsGet, Type: "page", Source: a.ConfigFile, Handler: http.HandlerFunc(a.handlePageRoute(pr))}
+		if pr.page != nil {
+			info.Auth, info.Roles, info.Component = pageInfo(*pr.page)
+		} else if len(pr.patterns) == 0 {
+			info.Component = a.NotFound
+		}
+		for _, pattern := range pr.patterns {
+			log.Println("Adding route for page:", pattern.page.Route)
+			sub := RouteInfo{Pattern: pattern.page.Route, Methods: methodsGet, Type: "page", Source: a.ConfigFile}
+			sub.Auth, sub.Roles, sub.Component = pageInfo(pattern.page)
+			info.Routes = append(info.Routes, sub)
+		}
+		a.Router.HandleRoute(info)
 	}
 	//Add routes for components, data and scripts
 	for _, comp := range a.Components {
-		comp.AddRoutesComponent(a.Mux, conn)
+		comp.AddRoutesComponent(a.Router, conn)
+		comp.AddRoutesData(a.Router, conn)
 		if a.Debug == true {
-			comp.AddRoutesScripts(a.Mux, a.RootPath)
+			comp.AddRoutesScripts(a.Router, a.RootPath)
 		}
 	}
 	if a.Debug == false {
-		a.LoadScriptCache()
+		if a.JsURL == "" {
+			a.LoadScriptCache()
+		}
 		log.Println("Adding route for script: /static/js/" + a.Title + ".js")
-		a.Mux.HandleFunc("/static/js/"+a.Title+".js", func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Cache-control", "max-age=90")
-			w.Header().Set("Last-Modified", a.StartTime.UTC().Format(http.TimeFormat))
-			w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
-			w.Header().Set("Content-Encoding", "gzip")
-			w.Write(a.JsCache)
-		})
+		a.Router.HandleFunc("/static/js/"+a.Title+".js", methodsGet, "script", handleAsset("application/javascript; charset=utf-8", a.JsCache, a.StartTime, false))
+		log.Println("Adding route for script:", a.JsURL)
+		a.Router.HandleFunc(a.JsURL, methodsGet, "script", handleAsset("application/javascript; charset=utf-8", a.JsCache, a.StartTime, true))
+		if a.JsSourceMap != nil {
+			log.Println("Adding route for source map:", a.SourceMapPath)
+			a.Router.HandleRoute(RouteInfo{Pattern: a.SourceMapPath, Methods: methodsGet, Type: "script", Auth: a.SourceMapAuth || len(a.SourceMapRoles) > 0, Roles: a.SourceMapRoles, Handler: http.HandlerFunc(a.handleSourceMap(a.JsSourceMap))})
+		}
+		for _, name := range a.componentNames() {
+			if chunk, ok := a.JsChunks[name]; ok {
+				a.addRoutesChunk(chunk, name)
+			}
+		}
 	} else {
 		//serve reload socket script
 		log.Println("Adding route for reload socket script: /static/js/reload.socket.js")
-		a.Mux.HandleFunc("/static/js/reload.socket.js", func(w http.ResponseWriter, r *http.Request) {
+		a.Router.HandleFunc("/static/js/reload.socket.js", methodsGet, "script", func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
 			w.Write(reloadSocketScript())
 		})
 	}
 
-	//Add route for templates
+	//Add route for stylesheet, rebuilt on reload
+	if a.CSSURL != "" {
+		log.Println("Adding route for style:", a.CSSURL)
+		a.Router.HandleRoute(RouteInfo{Pattern: a.CSSURL, Methods: methodsGet, Type: "static", Source: a.RootPath + a.MainCSSFile, Handler: http.HandlerFunc(handleAsset("text/css; charset=utf-8", a.CSSCache, a.StartTime, true))})
+	}
+
+	//Add route for templates, cache is rebuilt with routes on reload
+	var templateCache *Encoded
+	var templateCacheMutex sync.Mutex
 	log.Println("Adding route for template collection: /component/templates")
-	a.Mux.HandleFunc("/component/templates", func(w http.ResponseWriter, r *http.Request) {
-		if len(templateCache) == 0 {
+	a.Router.HandleFunc("/component/templates", methodsGet, "template", func(w http.ResponseWriter, r *http.Request) {
+		templateCacheMutex.Lock()
+		defer templateCacheMutex.Unlock()
+		if templateCache == nil {
 			tmpls := make(map[string]string)
 			for _, comp := range a.Components {
 				split := strings.Split(comp.Name, ".")
@@ -358,24 +723,26 @@ func (a *App) AddRoutes(conn db.Conn) error {
 				http.Error(w, "Internal server error", http.StatusInternalServerError)
 				return
 			}
-			bytes, err = Compress(bytes)
-			if err != nil {
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
-				return
-			}
+			encoded := NewEncoded(bytes)
 			log.Println("Serving: /component/templates: Compressed templates")
-			templateCache = bytes
+			templateCache = &encoded
 		} else {
 			log.Println("Serving: /component/templates from cache")
 		}
-		w.Header().Set("Content-Encoding", "gzip")
 		w.Header().Set("Cache-control", "max-age=90")
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		w.Write(templateCache)
+		templateCache.write(w, r, "application/json; charset=utf-8", a.StartTime)
 	})
 
 	//Add API routes
-	AddAPIRoutes(a.Mux, a.Conn)
+	addAPIRoutes(a.routes, a.Router, a.Conn)
+
+	//Add route for route table
+	if a.Debug == true {
+		log.Println("Adding route for route table: /_components/routes")
+		a.Router.HandleFunc("/_components/routes", methodsGet, "debug", a.handleRoutes)
+		log.Println("Adding route for load time: /_components/loaded")
+		a.Router.HandleFunc("/_components/loaded", methodsGet, "debug", a.handleLoaded)
+	}
 
 	return nil
 }
@@ -411,7 +778,11 @@ func (a *App) ScriptTags() string {
 			ret += "<script src=\"/static/js/reload.socket.js\"></script>\n"
 		}
 	} else {
-		ret = "<script src=\"/static/js/" + a.Title + ".js\"></script>\n"
+		src = a.JsURL
+		if src == "" {
+			src = "/static/js/" + a.Title + ".js"
+		}
+		ret = "<script src=\"" + src + "\"></script>\n"
 	}
 	return ret
 }
@@ -439,21 +810,35 @@ func (a *App) TemplateTags() string {
 
 //LoadScriptCache loads and crushes js files
 func (a *App) LoadScriptCache() {
-	a.JsCache = []byte("")
+	a.JsChunks = make(map[string]ScriptChunk)
 	if a.Webpack == false {
+		var files []string
 		for _, scriptPath := range a.Scripts {
 			if scriptPath[0] == '/' {
 				scriptPath = scriptPath[1:]
 			}
-			scriptPath = a.RootPath + scriptPath
-			a.JsCache = append(a.JsCache, loadJsFile(scriptPath)...)
+			files = append(files, a.RootPath+scriptPath)
 		}
-		var i int
-		for _, cmp := range a.Components {
-			for i = 0; i < len(cmp.JsFiles); i++ {
-				a.JsCache = append(a.JsCache, loadJsFile(cmp.JsFiles[i])...)
+		for _, name := range a.componentNames() {
+			cmp := a.Components[name]
+			if len(cmp.JsFiles) == 0 {
+				continue
+			}
+			if a.SplitScripts == true {
+				a.JsChunks[name] = a.bundleScripts(cmp.JsFiles, "/static/js/"+a.Title+"."+name+".js", "", "")
+			} else {
+				files = append(files, cmp.JsFiles...)
 			}
 		}
+		var loader string
+		if len(a.JsChunks) > 0 {
+			loader = chunkLoader
+		}
+		core := a.bundleScripts(files, "/static/js/"+a.Title+".js", a.SourceMapPath, loader)
+		a.JsURL = core.URL
+		a.JsCache = core.Cache
+		a.JsSourceMap = core.SourceMap
+		a.SourceMapPath = core.MapURL
 	} else {
 		scriptfile := "./static/js/" + a.Title + ".js"
 		content, err := ioutil.ReadFile(scriptfile)
@@ -461,18 +846,15 @@ func (a *App) LoadScriptCache() {
 			log.Println("Error reading file:", scriptfile, err)
 			content = []byte("")
 		}
-		a.JsCache = content
-	}
-	comp, err := Compress(a.JsCache)
-	if err == nil {
-		a.JsCache = comp
-		log.Println("Compressed script cache")
-	} else {
-		log.Println("Error compressing script cache")
+		a.JsURL = hashedURL("/static/js/"+a.Title+".js", contentHash(content))
+		a.JsCache = NewEncoded(content)
+		a.JsSourceMap = nil
 	}
+	log.Println("Compressed script cache")
 }
 
-func loadJsFile(path string) []byte {
+//loadJsFile loads minified js file, adds file to source map when sm is not nil
+func loadJsFile(path, source string, sm *sourceMap) []byte {
 	bytes, err := ioutil.ReadFile(path)
 	log.Println("Loading script cache:", path)
 	if err != nil {
@@ -485,6 +867,9 @@ func loadJsFile(path string) []byte {
 		minified = string(bytes)
 		log.Println("ERROR minifying js file:", err)
 	}
+	if sm != nil {
+		sm.add(source, bytes, []byte(minified))
+	}
 	return []byte(minified)
 }
 
@@ -504,25 +889,25 @@ func Compress(inp []byte) ([]byte, error) {
 
 //GetRequestArgs build arguments from http request
 func GetRequestArgs(r *http.Request) map[string]string {
-	var err error
-	payload := make(map[string]string)
 	args := make(map[string]string)
-	args["locale"] = "nl"
 	args["path"] = r.URL.Path
-	//var locale = "nl"
-	token, err := jwt.GetToken(r)
+	for k, v := range GetClaims(r) {
+		args[k] = v
+	}
+	args["locale"] = RequestLocale(r)
+	return args
+}
+
+//GetClaims gets claims of authenticated principal from http request
+func GetClaims(r *http.Request) map[string]string {
+	claims := make(map[string]string)
+	principal, err := Authenticate(r)
 	if err == nil {
-		payload, err = jwt.GetPayload(token)
-		if err == nil {
-			for k, v := range payload {
-				args[k] = v
-			}
+		for k, v := range principal.Claims {
+			claims[k] = v
 		}
 	}
-	if loc, ok := r.URL.Query()["locale"]; ok {
-		args["locale"] = strings.Join(loc, "")
-	}
-	return args
+	return claims
 }
 
 //RunWebpack runs webpack command
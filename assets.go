@@ -0,0 +1,99 @@
+package components
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tdewolff/minify"
+	cssmin "github.com/tdewolff/minify/css"
+)
+
+//cacheImmutable Cache-Control for content hashed urls
+const cacheImmutable = "public, max-age=31536000, immutable"
+
+//contentHash returns short hash of content for asset urls
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])[:10]
+}
+
+//hashedURL adds hash before extension: /static/js/Example.js => /static/js/Example.3f9a1c2b4d.js
+func hashedURL(url, hash string) string {
+	ext := path.Ext(url)
+	return strings.TrimSuffix(url, ext) + "." + hash + ext
+}
+
+//LoadStyleCache builds minified stylesheet from compiled css (MainCSSFile) and .css files of components,
+//served with content hashed url. with ScopeStyles selectors of components are prefixed with the component tag
+func (a *App) LoadStyleCache() {
+	a.CSSCache = Encoded{}
+	a.CSSURL = ""
+	var css string
+	if content, err := ioutil.ReadFile(a.RootPath + a.MainCSSFile); err == nil {
+		css = string(content) + "\n"
+	} else if !os.IsNotExist(err) {
+		log.Println("Error reading css file:", a.MainCSSFile, err)
+	}
+	for _, name := range a.componentNames() {
+		for _, file := range a.Components[name].StyleFiles {
+			if filepath.Ext(file) != ".css" {
+				continue
+			}
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				log.Println("Error reading css file:", file, err)
+				continue
+			}
+			log.Println("Loading style cache:", file)
+			if a.ScopeStyles == true {
+				css += scopeCSS(string(content), scopeSelector(name)) + "\n"
+			} else {
+				css += string(content) + "\n"
+			}
+		}
+	}
+	if css == "" {
+		return
+	}
+	m := minify.New()
+	m.AddFunc("text/css", cssmin.Minify)
+	minified, err := m.String("text/css", css)
+	if err != nil {
+		log.Println("ERROR minifying css:", err)
+		minified = css
+	}
+	a.CSSURL = hashedURL("/"+a.MainCSSFile, contentHash([]byte(minified)))
+	a.CSSCache = NewEncoded([]byte(minified))
+	log.Println("Loaded style cache:", a.CSSURL)
+}
+
+//hashStyleURL replaces links to MainCSSFile in layout html with content hashed url
+func (a *App) hashStyleURL(html string) string {
+	if a.CSSURL == "" {
+		return html
+	}
+	for _, q := range []string{"\"", "'"} {
+		html = strings.Replace(html, q+"/"+a.MainCSSFile+q, q+a.CSSURL+q, -1)
+	}
+	return html
+}
+
+//handleAsset serves asset in encoding accepted by client, long lived when url is content hashed
+func handleAsset(contentType string, content Encoded, modified time.Time, hashed bool) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if hashed == true {
+			w.Header().Set("Cache-Control", cacheImmutable)
+		} else {
+			w.Header().Set("Cache-control", "max-age=90")
+		}
+		content.write(w, r, contentType, modified)
+	}
+}
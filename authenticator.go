@@ -0,0 +1,393 @@
+package components
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"git.muysers.nl/jmu0/jwt"
+)
+
+//ErrNotAuthenticated error when request has no valid credentials
+var ErrNotAuthenticated = errors.New("Not authenticated")
+
+//ErrLoginNotSupported error when authenticator can't start sessions
+var ErrLoginNotSupported = errors.New("Login not supported")
+
+//Principal authenticated user
+type Principal struct {
+	Name   string
+	Claims map[string]string
+}
+
+//Authenticator authenticates http requests
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+//LoginHandler is implemented by authenticators that start and end sessions for /auth/ routes
+type LoginHandler interface {
+	Login(w http.ResponseWriter, r *http.Request, p *Principal) error
+	Logout(w http.ResponseWriter, r *http.Request)
+	Refresh(w http.ResponseWriter, r *http.Request) error
+}
+
+//Challenger is implemented by authenticators that add headers to 401 responses
+type Challenger interface {
+	Challenge(w http.ResponseWriter)
+}
+
+var authenticator Authenticator = &JWTAuthenticator{}
+
+type principalKey struct{}
+
+type principalValue struct {
+	principal *Principal
+	err       error
+}
+
+//SetAuthenticator sets authenticator for requests not served by an App (App uses App.Authenticator). default: JWTAuthenticator
+func SetAuthenticator(a Authenticator) {
+	if a == nil {
+		a = &JWTAuthenticator{}
+	}
+	authenticator = a
+}
+
+//Authenticate authenticates request with the authenticator of the App serving the request
+func Authenticate(r *http.Request) (*Principal, error) {
+	if v, ok := r.Context().Value(principalKey{}).(principalValue); ok {
+		return v.principal, v.err
+	}
+	p, err := requestAuthenticator(r).Authenticate(r)
+	if err == nil && p == nil {
+		err = ErrNotAuthenticated
+	}
+	return p, err
+}
+
+//WithPrincipal returns request with principal, used instead of authenticator (ex: for testing)
+func WithPrincipal(r *http.Request, p *Principal) *http.Request {
+	v := principalValue{principal: p}
+	if p == nil {
+		v.err = ErrNotAuthenticated
+	}
+	return r.WithContext(context.WithValue(r.Context(), principalKey{}, v))
+}
+
+//authenticateRequest authenticates request once and stores result in request context
+func authenticateRequest(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(principalKey{}).(principalValue); ok {
+		return r
+	}
+	p, err := Authenticate(r)
+	return r.WithContext(context.WithValue(r.Context(), principalKey{}, principalValue{principal: p, err: err}))
+}
+
+//requestAuthenticator returns authenticator of the App serving the request, default authenticator otherwise
+func requestAuthenticator(r *http.Request) Authenticator {
+	if s := requestSettings(r.Context()); s != nil && s.authenticator != nil {
+		return s.authenticator
+	}
+	return authenticator
+}
+
+//challenge adds authenticator headers to 401 response
+func challenge(w http.ResponseWriter, r *http.Request) {
+	if c, ok := requestAuthenticator(r).(Challenger); ok {
+		c.Challenge(w)
+	}
+}
+
+//JWTAuthenticator authenticates requests with jwt token.
+//without Secret tokens are checked by the jwt package, with Secret it issues and checks HS256 tokens
+type JWTAuthenticator struct {
+	Secret   []byte
+	Lifetime time.Duration //default: 24h
+	Cookie   string        //set token in HttpOnly cookie with this name instead of response body
+}
+
+//Authenticate gets principal from jwt payload
+func (j *JWTAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if len(j.Secret) > 0 {
+		return j.verify(j.token(r))
+	}
+	if jwt.Authenticated(r) == false {
+		return nil, ErrNotAuthenticated
+	}
+	token, err := jwt.GetToken(r)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := jwt.GetPayload(token)
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{Name: payload["name"], Claims: payload}, nil
+}
+
+//Login issues token for principal
+func (j *JWTAuthenticator) Login(w http.ResponseWriter, r *http.Request, p *Principal) error {
+	if len(j.Secret) == 0 {
+		return ErrLoginNotSupported
+	}
+	lifetime := j.Lifetime
+	if lifetime == 0 {
+		lifetime = 24 * time.Hour
+	}
+	expires := time.Now().Add(lifetime)
+	token, err := j.sign(p.Claims, expires)
+	if err != nil {
+		return err
+	}
+	if j.Cookie != "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     j.Cookie,
+			Value:    token,
+			Path:     "/",
+			Expires:  expires,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		return nil
+	}
+	w.Header().Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+//Logout removes token cookie
+func (j *JWTAuthenticator) Logout(w http.ResponseWriter, r *http.Request) {
+	if j.Cookie != "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     j.Cookie,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+		})
+	}
+}
+
+//Refresh issues new token for authenticated request
+func (j *JWTAuthenticator) Refresh(w http.ResponseWriter, r *http.Request) error {
+	p, err := j.Authenticate(r)
+	if err != nil {
+		return err
+	}
+	return j.Login(w, r, p)
+}
+
+//token gets token from Authorization header or cookie
+func (j *JWTAuthenticator) token(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimPrefix(h, "Bearer ")
+	}
+	if j.Cookie != "" {
+		if cookie, err := r.Cookie(j.Cookie); err == nil {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+//sign creates HS256 token
+func (j *JWTAuthenticator) sign(claims map[string]string, expires time.Time) (string, error) {
+	payload := make(map[string]interface{})
+	for k, v := range claims {
+		payload[k] = v
+	}
+	payload["exp"] = expires.Unix()
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	return token + "." + j.signature(token), nil
+}
+
+func (j *JWTAuthenticator) signature(s string) string {
+	mac := hmac.New(sha256.New, j.Secret)
+	mac.Write([]byte(s))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+//verify checks HS256 token, returns principal
+func (j *JWTAuthenticator) verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrNotAuthenticated
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(j.signature(parts[0]+"."+parts[1]))) {
+		return nil, ErrNotAuthenticated
+	}
+	body, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+	var payload map[string]interface{}
+	err = json.Unmarshal(body, &payload)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+	exp, ok := payload["exp"].(float64)
+	if !ok || time.Now().Unix() > int64(exp) {
+		return nil, ErrNotAuthenticated
+	}
+	claims := make(map[string]string)
+	for k, v := range payload {
+		if k != "exp" {
+			claims[k] = toString(v)
+		}
+	}
+	return &Principal{Name: claims["name"], Claims: claims}, nil
+}
+
+//AuthFunc checks username and password, returns claims for user
+type AuthFunc func(username, password string) (map[string]string, error)
+
+//BasicAuthenticator authenticates requests with http basic auth
+type BasicAuthenticator struct {
+	Realm string
+	Check AuthFunc
+}
+
+//Authenticate checks basic auth username and password
+func (b *BasicAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok || b.Check == nil {
+		return nil, ErrNotAuthenticated
+	}
+	claims, err := b.Check(username, password)
+	if err != nil {
+		return nil, err
+	}
+	if claims == nil {
+		claims = make(map[string]string)
+	}
+	if _, ok := claims["name"]; !ok {
+		claims["name"] = username
+	}
+	return &Principal{Name: claims["name"], Claims: claims}, nil
+}
+
+//Challenge asks browser for username and password
+func (b *BasicAuthenticator) Challenge(w http.ResponseWriter) {
+	realm := b.Realm
+	if realm == "" {
+		realm = "Restricted"
+	}
+	w.Header().Set("WWW-Authenticate", "Basic realm=\""+realm+"\"")
+}
+
+type session struct {
+	principal *Principal
+	expires   time.Time
+}
+
+//SessionAuthenticator authenticates requests with session cookie, sessions are stored in memory
+type SessionAuthenticator struct {
+	Cookie   string        //cookie name, default: session
+	Lifetime time.Duration //default: 24h
+	sessions map[string]session
+	mutex    sync.Mutex
+}
+
+func (s *SessionAuthenticator) cookieName() string {
+	if s.Cookie == "" {
+		return "session"
+	}
+	return s.Cookie
+}
+
+//Authenticate gets principal from session
+func (s *SessionAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	cookie, err := r.Cookie(s.cookieName())
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	sess, ok := s.sessions[cookie.Value]
+	if !ok {
+		return nil, ErrNotAuthenticated
+	}
+	if time.Now().After(sess.expires) {
+		delete(s.sessions, cookie.Value)
+		return nil, ErrNotAuthenticated
+	}
+	return sess.principal, nil
+}
+
+//Login creates session for principal and sets session cookie
+func (s *SessionAuthenticator) Login(w http.ResponseWriter, r *http.Request, p *Principal) error {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return err
+	}
+	id := hex.EncodeToString(b)
+	lifetime := s.Lifetime
+	if lifetime == 0 {
+		lifetime = 24 * time.Hour
+	}
+	s.mutex.Lock()
+	if s.sessions == nil {
+		s.sessions = make(map[string]session)
+	}
+	now := time.Now()
+	for k, sess := range s.sessions {
+		if now.After(sess.expires) {
+			delete(s.sessions, k)
+		}
+	}
+	s.sessions[id] = session{principal: p, expires: now.Add(lifetime)}
+	s.mutex.Unlock()
+	http.SetCookie(w, &http.Cookie{
+		Name:     s.cookieName(),
+		Value:    id,
+		Path:     "/",
+		Expires:  now.Add(lifetime),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+//Logout removes session and session cookie
+func (s *SessionAuthenticator) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(s.cookieName()); err == nil {
+		s.mutex.Lock()
+		delete(s.sessions, cookie.Value)
+		s.mutex.Unlock()
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     s.cookieName(),
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+}
+
+//Refresh extends session
+func (s *SessionAuthenticator) Refresh(w http.ResponseWriter, r *http.Request) error {
+	p, err := s.Authenticate(r)
+	if err != nil {
+		return err
+	}
+	s.Logout(w, r)
+	return s.Login(w, r, p)
+}
@@ -0,0 +1,57 @@
+package components
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJWTSignVerify(t *testing.T) {
+	j := &JWTAuthenticator{Secret: []byte("secret")}
+	token, err := j.sign(map[string]string{"name": "user", "klant": "k1"}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := j.verify(token)
+	if err != nil || p.Name != "user" || p.Claims["klant"] != "k1" {
+		t.Fatalf("verify: %v %v", p, err)
+	}
+	if _, ok := p.Claims["exp"]; ok {
+		t.Error("exp in claims")
+	}
+	other := &JWTAuthenticator{Secret: []byte("other")}
+	if _, err := other.verify(token); err == nil {
+		t.Error("token of other secret accepted")
+	}
+	parts := strings.Split(token, ".")
+	tampered, _ := j.sign(map[string]string{"name": "admin"}, time.Now().Add(time.Hour))
+	if _, err := j.verify(parts[0] + "." + strings.Split(tampered, ".")[1] + "." + parts[2]); err == nil {
+		t.Error("tampered token accepted")
+	}
+	expired, _ := j.sign(map[string]string{"name": "user"}, time.Now().Add(-time.Minute))
+	if _, err := j.verify(expired); err == nil {
+		t.Error("expired token accepted")
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	if p, err := j.Authenticate(r); err != nil || p.Name != "user" {
+		t.Errorf("authenticate header: %v %v", p, err)
+	}
+}
+
+func TestRequestAuthenticator(t *testing.T) {
+	j := &JWTAuthenticator{Secret: []byte("secret")}
+	token, _ := j.sign(map[string]string{"name": "user"}, time.Now().Add(time.Hour))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	if _, err := Authenticate(r); err == nil {
+		t.Error("default authenticator accepted token of app secret")
+	}
+	r = r.WithContext(context.WithValue(r.Context(), settingsKey{}, &appSettings{authenticator: j}))
+	if p, err := Authenticate(r); err != nil || p.Name != "user" {
+		t.Errorf("authenticator of app: %v %v", p, err)
+	}
+}
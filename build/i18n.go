@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/jmu0/components"
+	yaml "gopkg.in/yaml.v2"
+)
+
+//buildI18n adds {{t:key}} markers in templates to locale/<locale>.yml of component, existing translations are kept
+func buildI18n() {
+	var paths []string
+	if app.ComponentPaths != nil {
+		paths = app.ComponentPaths
+	} else {
+		paths = append(paths, app.ComponentsPath)
+	}
+	for _, path := range paths {
+		//layouts in components path
+		var keys []string
+		files, err := filepath.Glob(app.RootPath + path + "/*.html")
+		if err != nil {
+			fmt.Println("ERROR:", err)
+		}
+		for _, file := range files {
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				fmt.Println("ERROR:", err)
+				continue
+			}
+			keys = append(keys, components.TranslationKeys(string(content))...)
+		}
+		writeCatalog(app.RootPath+path, keys)
+	}
+	for _, cmp := range app.Components {
+		var keys []string
+		for _, tmpl := range cmp.TemplateManager.GetTemplates() {
+			keys = append(keys, components.TranslationKeys(tmpl.HTML)...)
+		}
+		writeCatalog(cmp.Path, keys)
+	}
+}
+
+//writeCatalog writes missing keys to <path>/locale/<locale>.yml for every locale
+func writeCatalog(path string, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	for _, locale := range app.Locales {
+		file := path + "/locale/" + locale + ".yml"
+		entries := make(map[string]string)
+		if _, err := os.Stat(file); err == nil {
+			entries, err = components.ReadCatalogFile(file)
+			if err != nil {
+				fmt.Println("ERROR:", err)
+				continue
+			}
+		}
+		added := 0
+		for _, key := range keys {
+			if _, ok := entries[key]; !ok {
+				entries[key] = ""
+				added++
+			}
+		}
+		if added == 0 {
+			continue
+		}
+		content, err := yaml.Marshal(entries)
+		if err != nil {
+			fmt.Println("ERROR:", err)
+			continue
+		}
+		err = os.MkdirAll(path+"/locale", 0770)
+		if err != nil {
+			fmt.Println("ERROR:", err)
+			continue
+		}
+		fmt.Println("Adding", added, "keys to", file)
+		err = ioutil.WriteFile(file, content, 0660)
+		if err != nil {
+			fmt.Println("ERROR:", err)
+		}
+	}
+}
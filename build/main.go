@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -125,6 +126,27 @@ func main() {
 		if app.Webpack == true {
 			app.RunWebpack()
 		}
+	case "i18n":
+		app = loadApp()
+		buildI18n()
+	case "routes":
+		app = loadApp()
+		app.Router = components.NewRouter()
+		err = app.AddRoutes(nil)
+		if err != nil {
+			fmt.Println("ERROR AddRoutes:", err)
+			os.Exit(1)
+		}
+		if len(os.Args) > 2 && os.Args[2] == "json" {
+			bytes, err := json.MarshalIndent(app.Routes(), "", "  ")
+			if err != nil {
+				fmt.Println("ERROR:", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(bytes))
+		} else {
+			components.WriteRoutes(os.Stdout, app.Routes())
+		}
 	case "run":
 		defer func() {
 			log.Println("DEFERRING...")
@@ -139,6 +161,8 @@ func printHelp() {
 	fmt.Print("Invalid Arguments. Usage:\n\n")
 	fmt.Print("Build .less import file for all components: \n\tbuild less [<outfile>] [<mainfile>]\n\n")
 	fmt.Print("Build .js file from components: \n\tbuild js [outfile | debug] [debug]\n\n")
+	fmt.Print("Add translation keys ({{t:key}}) in templates to locale/<locale>.yml files: \n\tbuild i18n\n\n")
+	fmt.Print("List page, component, template, script and api routes: \n\tbuild routes [json]\n\n")
 	fmt.Print("Run development server: \n\tbuild run\n\n")
 }
 func loadApp() components.App {
@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
@@ -84,10 +85,22 @@ func watch() {
 				if checkExtension(event.Name()) {
 					log.Println("Change detected:", strings.Replace(event.Path, wd, "", -1))
 					if filepath.Ext(event.Name()) == ".go" {
+						loaded := appLoaded()
 						kill()
 						build()
 						start()
-						reload()
+						if app.Debug == true {
+							reloadWhenLoaded(loaded)
+						} else {
+							reload()
+						}
+					} else if filepath.Ext(event.Name()) == ".html" || filepath.Ext(event.Name()) == ".yml" || filepath.Ext(event.Name()) == ".css" {
+						//app reloads components and config in debug mode, browser reloads after app
+						if app.Debug == true {
+							reloadWhenLoaded(appLoaded())
+						} else {
+							reload()
+						}
 					} else if filepath.Ext(event.Name()) == ".scss" {
 						buildSass()
 						reload()
@@ -133,6 +146,45 @@ func reload() {
 	SendSocketMessage([]byte("reload"))
 }
 
+//appLoaded returns load time of running app (debug mode), empty when app is not running
+func appLoaded() string {
+	host := app.Port
+	if host == "" {
+		host = ":8080"
+	}
+	if strings.HasPrefix(host, ":") {
+		host = "localhost" + host
+	}
+	client := http.Client{Timeout: time.Second}
+	res, err := client.Get("http://" + host + "/_components/loaded")
+	if err != nil {
+		return ""
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return ""
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return ""
+	}
+	return string(body)
+}
+
+//reloadWhenLoaded reloads browser when app has (re)loaded after load time before, or after 5s
+func reloadWhenLoaded(before string) {
+	go func() {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			time.Sleep(100 * time.Millisecond)
+			if loaded := appLoaded(); loaded != "" && loaded != before {
+				break
+			}
+		}
+		reload()
+	}()
+}
+
 func buildSass() {
 	log.Println("Compiling sass:", "sass", app.MainSassFile+":"+app.MainCSSFile)
 	cmd = exec.Command("sass", app.MainSassFile+":"+app.MainCSSFile)
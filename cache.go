@@ -0,0 +1,236 @@
+package components
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmu0/dbAPI/db"
+)
+
+//Cache caches data of components and query routes. entries are tagged with the tables they depend on
+type Cache interface {
+	Get(key string) ([]map[string]interface{}, bool)
+	Set(key string, data []map[string]interface{}, ttl time.Duration, tables []string)
+	Invalidate(table string) //removes entries depending on table
+}
+
+var dataCache Cache = NewLRUCache(1000)
+
+//SetCache sets cache for component and query data of requests not served by an App (App uses App.Cache).
+//default: LRUCache with 1000 entries
+func SetCache(c Cache) {
+	if c == nil {
+		c = NewLRUCache(1000)
+	}
+	dataCache = c
+}
+
+//InvalidateTable removes data depending on table from cache set with SetCache, ex: Assortiment.Plant or Plant.
+//use App.Cache.Invalidate for the cache of an App
+func InvalidateTable(table string) {
+	dataCache.Invalidate(table)
+}
+
+//requestCache returns cache of the App serving the request, cache set with SetCache otherwise
+func requestCache(ctx context.Context) Cache {
+	if s := requestSettings(ctx); s != nil && s.cache != nil {
+		return s.cache
+	}
+	return dataCache
+}
+
+//LRUCache in memory cache, least recently used entries are removed when full
+type LRUCache struct {
+	size   int
+	items  map[string]*list.Element
+	order  *list.List                 //most recently used first
+	tables map[string]map[string]bool //table => keys
+	mutex  sync.Mutex
+}
+
+//cacheEntry entry in LRUCache
+type cacheEntry struct {
+	key     string
+	data    []map[string]interface{}
+	expires time.Time
+	tables  []string
+}
+
+//NewLRUCache creates cache for size entries
+func NewLRUCache(size int) *LRUCache {
+	if size < 1 {
+		size = 1000
+	}
+	return &LRUCache{
+		size:   size,
+		items:  make(map[string]*list.Element),
+		order:  list.New(),
+		tables: make(map[string]map[string]bool),
+	}
+}
+
+//Get returns copy of cached rows
+func (c *LRUCache) Get(key string) ([]map[string]interface{}, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return copyRows(entry.data), true
+}
+
+//Set stores copy of rows for ttl
+func (c *LRUCache) Set(key string, data []map[string]interface{}, ttl time.Duration, tables []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	entry := &cacheEntry{key: key, data: copyRows(data), expires: time.Now().Add(ttl)}
+	for _, table := range tables {
+		table = tableName(table)
+		entry.tables = append(entry.tables, table)
+		if c.tables[table] == nil {
+			c.tables[table] = make(map[string]bool)
+		}
+		c.tables[table][key] = true
+	}
+	c.items[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+//Invalidate removes entries depending on table
+func (c *LRUCache) Invalidate(table string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key := range c.tables[tableName(table)] {
+		if el, ok := c.items[key]; ok {
+			c.remove(el)
+		}
+	}
+}
+
+//remove removes entry, mutex must be locked
+func (c *LRUCache) remove(el *list.Element) {
+	entry := el.Value.(*cacheEntry)
+	c.order.Remove(el)
+	delete(c.items, entry.key)
+	for _, table := range entry.tables {
+		delete(c.tables[table], entry.key)
+		if len(c.tables[table]) == 0 {
+			delete(c.tables, table)
+		}
+	}
+}
+
+//tableName returns lower case table name without schema and quotes. tables with the same name
+//in different schemas share entries, invalidating too much is safe
+func tableName(table string) string {
+	table = strings.ToLower(strings.Trim(table, "`\"[] "))
+	spl := strings.Split(table, ".")
+	return strings.Trim(spl[len(spl)-1], "`\"[] ")
+}
+
+var sqlTables = regexp.MustCompile("(?i)\\b(?:from|join|into|update)\\s+([\\w.`\"\\[\\]]+)")
+
+//queryTables returns tables used in query (from, join, into, update)
+func queryTables(query string) []string {
+	var ret []string
+	found := make(map[string]bool)
+	for _, m := range sqlTables.FindAllStringSubmatch(query, -1) {
+		table := tableName(m[1])
+		if table != "" && !found[table] {
+			found[table] = true
+			ret = append(ret, table)
+		}
+	}
+	return ret
+}
+
+//parseTTL parses cache ttl, ex: 30s, 5m. empty: no caching
+func parseTTL(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
+
+//cacheKey returns key for name and arguments
+func cacheKey(name string, args map[string]string, keys []string) string {
+	names := make([]string, 0, len(args))
+	for k := range args {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	key := name
+	for _, k := range names {
+		key += "\x00" + k + "=" + args[k]
+	}
+	return key + "\x00" + strings.Join(keys, ":")
+}
+
+//cachedDataFunc caches data of data func for ttl. key: component name, path, locale, claims and path parameters
+func cachedDataFunc(name string, ttl time.Duration, tables []string, f DataFuncCtx) DataFuncCtx {
+	return func(ctx context.Context, req *DataRequest, conn db.Conn) ([]map[string]interface{}, error) {
+		key := cacheKey("component:"+name, req.Args, req.Keys)
+		cache := requestCache(ctx)
+		if data, ok := cache.Get(key); ok {
+			return data, nil
+		}
+		data, err := f(ctx, req, conn)
+		if err == nil {
+			cache.Set(key, data, ttl, tables)
+		}
+		return data, err
+	}
+}
+
+//queryCacheKey returns key for query with bind arguments. path parameters and claims used in query are bind arguments
+func queryCacheKey(query string, args []interface{}) string {
+	return "query:" + query + "\x00" + fmt.Sprintf("%#v", args)
+}
+
+//restTable returns table of rest request: /api/<schema>/<table>/<key>
+func restTable(path string) string {
+	spl := strings.Split(strings.Trim(strings.TrimPrefix(path, apiURL), "/"), "/")
+	if len(spl) < 2 {
+		return ""
+	}
+	return spl[0] + "." + spl[1]
+}
+
+//statusWriter remembers status of response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if sw.status == 0 {
+		sw.status = status
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	return sw.ResponseWriter.Write(b)
+}
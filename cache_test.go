@@ -0,0 +1,63 @@
+package components
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLRUCache(t *testing.T) {
+	c := NewLRUCache(2)
+	rows := []map[string]interface{}{{"id": "1"}}
+	c.Set("a", rows, time.Minute, []string{"Assortiment.Plant"})
+	c.Set("b", rows, time.Minute, []string{"Maat"})
+	data, ok := c.Get("a")
+	if !ok || !reflect.DeepEqual(data, rows) {
+		t.Fatalf("get: %v %v", data, ok)
+	}
+	data[0]["id"] = "changed"
+	if data, _ := c.Get("a"); data[0]["id"] != "1" {
+		t.Error("cached rows changed by caller")
+	}
+	//b is least recently used
+	c.Set("c", rows, time.Minute, nil)
+	if _, ok := c.Get("b"); ok {
+		t.Error("least recently used entry not removed")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("recently used entry removed")
+	}
+	c.Set("d", rows, -time.Second, nil)
+	if _, ok := c.Get("d"); ok {
+		t.Error("expired entry returned")
+	}
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	c := NewLRUCache(10)
+	rows := []map[string]interface{}{{"id": "1"}}
+	c.Set("plant", rows, time.Minute, []string{"Assortiment.Plant"})
+	c.Set("join", rows, time.Minute, []string{"`Plant`", "Maat"})
+	c.Set("maat", rows, time.Minute, []string{"Maat"})
+	c.Invalidate("plant")
+	for key, want := range map[string]bool{"plant": false, "join": false, "maat": true} {
+		if _, ok := c.Get(key); ok != want {
+			t.Errorf("%s: cached %v, want %v", key, ok, want)
+		}
+	}
+	if tables := queryTables("select * from Assortiment.Plant p join `Maat` m on m.id = p.maat"); !reflect.DeepEqual(tables, []string{"plant", "maat"}) {
+		t.Errorf("query tables: %v", tables)
+	}
+}
+
+func TestRequestCache(t *testing.T) {
+	c := NewLRUCache(10)
+	ctx := context.WithValue(context.Background(), settingsKey{}, &appSettings{cache: c})
+	if requestCache(ctx) != c {
+		t.Error("cache of app not used")
+	}
+	if requestCache(context.Background()) != dataCache {
+		t.Error("default cache not used")
+	}
+}
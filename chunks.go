@@ -0,0 +1,182 @@
+package components
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+//ScriptChunk minified script bundle at content hashed url
+type ScriptChunk struct {
+	URL       string
+	Cache     Encoded
+	SourceMap []byte //nil without source_map
+	MapURL    string
+}
+
+//bundleScripts minifies and concatenates files into chunk for url, suffix is added after the files.
+//source map is served at mapURL, default: <chunk url>.map
+func (a *App) bundleScripts(files []string, url, mapURL, suffix string) ScriptChunk {
+	var sm *sourceMap
+	if a.SourceMap == true {
+		sm = &sourceMap{}
+	}
+	script := []byte("")
+	for _, file := range files {
+		source := strings.TrimPrefix(strings.Replace(file, a.RootPath, "", 1), "/")
+		script = append(script, loadJsFile(file, source, sm)...)
+	}
+	script = append(script, suffix...)
+	chunk := ScriptChunk{URL: hashedURL(url, contentHash(script))}
+	if sm != nil {
+		if mapURL == "" {
+			mapURL = chunk.URL + ".map"
+		}
+		smap, err := sm.JSON(path.Base(chunk.URL))
+		if err != nil {
+			log.Println("Error building source map:", err)
+		} else {
+			chunk.SourceMap = smap
+			chunk.MapURL = mapURL
+			script = append(script, []byte("\n//# sourceMappingURL="+mapURL)...)
+		}
+	}
+	chunk.Cache = NewEncoded(script)
+	return chunk
+}
+
+//componentNames returns sorted names of components, bundles don't change between loads
+func (a *App) componentNames() []string {
+	names := make([]string, 0, len(a.Components))
+	for name := range a.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//addRoutesChunk adds routes for script chunk and its source map
+func (a *App) addRoutesChunk(chunk ScriptChunk, component string) {
+	log.Println("Adding route for script:", chunk.URL)
+	a.Router.HandleRoute(RouteInfo{Pattern: chunk.URL, Methods: methodsGet, Type: "script", Component: component, Handler: http.HandlerFunc(handleAsset("application/javascript; charset=utf-8", chunk.Cache, a.StartTime, true))})
+	if chunk.SourceMap != nil {
+		log.Println("Adding route for source map:", chunk.MapURL)
+		a.Router.HandleRoute(RouteInfo{Pattern: chunk.MapURL, Methods: methodsGet, Type: "script", Auth: a.SourceMapAuth || len(a.SourceMapRoles) > 0, Roles: a.SourceMapRoles, Component: component, Handler: http.HandlerFunc(a.handleSourceMap(chunk.SourceMap))})
+	}
+}
+
+//componentNames returns names of components in part tree of page, in order of first use
+func (p *Page) componentNames() []string {
+	var names []string
+	found := make(map[string]bool)
+	var walk func(parts []Part)
+	walk = func(parts []Part) {
+		for _, part := range parts {
+			if !found[part.Name] {
+				found[part.Name] = true
+				names = append(names, part.Name)
+			}
+			walk(part.Components)
+		}
+	}
+	walk(p.Components)
+	return names
+}
+
+//chunksJSON returns component tag => chunk url
+func (a *App) chunksJSON() string {
+	chunks := make(map[string]string)
+	for name, chunk := range a.JsChunks {
+		chunks[strings.ToLower(name)] = chunk.URL
+	}
+	bytes, err := json.Marshal(chunks)
+	if err != nil {
+		log.Println("Error building chunks json:", err)
+		return "{}"
+	}
+	return string(bytes)
+}
+
+//addScriptChunks adds module script tags for chunks of components and chunk urls for the loader in the core bundle
+//to layout data, modulepreload hints as ${{preload}}. chunks are modules like component scripts in debug mode
+func (a *App) addScriptChunks(data map[string]interface{}, components []string) {
+	data["preload"] = ""
+	if len(a.JsChunks) == 0 {
+		return
+	}
+	var scripts, preload string
+	loaded := make([]string, 0)
+	for _, name := range components {
+		if chunk, ok := a.JsChunks[name]; ok {
+			scripts += "<script type=\"module\" src=\"" + chunk.URL + "\"></script>\n"
+			preload += "<link rel=\"modulepreload\" href=\"" + chunk.URL + "\">\n"
+			loaded = append(loaded, strings.ToLower(name))
+		}
+	}
+	bytes, err := json.Marshal(map[string]interface{}{"urls": json.RawMessage(a.chunksJSON()), "loaded": loaded})
+	if err != nil {
+		log.Println("Error building chunks json:", err)
+		bytes = []byte(`{"urls":{},"loaded":[]}`)
+	}
+	//before the core bundle, json escapes < and >
+	chunks := "<script type=\"application/json\" id=\"component-chunks\">" + string(bytes) + "</script>\n"
+	data["scripts"] = chunks + fmt.Sprint(data["scripts"]) + scripts
+	data["preload"] = preload
+}
+
+//chunkLoader is added to the core bundle. loads chunks of components in pages reached by client side navigation:
+//loadChunks(names) returns promise (window.loadChunks, m.loadChunks when the core bundle has m),
+//chunks of components added to the page (tag or data-component) are loaded automatically.
+//chunk urls and chunks loaded by the page are read from the #component-chunks json
+const chunkLoader = `
+;(function () {
+    var chunks;
+    function state() {
+        if (chunks === undefined) {
+            var el = document.getElementById("component-chunks");
+            var data = el ? JSON.parse(el.textContent) : {urls: {}, loaded: []};
+            chunks = {urls: data.urls, loaded: {}};
+            data.loaded.forEach(function (name) { chunks.loaded[name] = Promise.resolve(); });
+        }
+        return chunks;
+    }
+    function loadChunks(names) {
+        var c = state();
+        return Promise.all(names.map(function (name) {
+            name = name.toLowerCase();
+            var url = c.urls[name];
+            if (url === undefined) return Promise.resolve();
+            if (c.loaded[name] === undefined) {
+                c.loaded[name] = new Promise(function (resolve, reject) {
+                    var script = document.createElement("script");
+                    script.type = "module";
+                    script.src = url;
+                    script.onload = resolve;
+                    script.onerror = reject;
+                    document.body.appendChild(script);
+                });
+            }
+            return c.loaded[name];
+        }));
+    }
+    function observe() {
+        new MutationObserver(function (mutations) {
+            mutations.forEach(function (mutation) {
+                mutation.addedNodes.forEach(function (node) {
+                    if (node.nodeType !== 1) return;
+                    var names = [node.tagName];
+                    node.querySelectorAll("[data-component]").forEach(function (el) { names.push(el.tagName); });
+                    loadChunks(names).catch(function (err) { console.error("Error loading chunk:", err); });
+                });
+            });
+        }).observe(document.body, {childList: true, subtree: true});
+    }
+    if (document.body) observe(); else document.addEventListener("DOMContentLoaded", observe);
+    window.loadChunks = loadChunks;
+    if (typeof m === "object" && m !== null) m.loadChunks = loadChunks;
+})();
+`
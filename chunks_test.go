@@ -0,0 +1,24 @@
+package components
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAddScriptChunks(t *testing.T) {
+	a := &App{JsChunks: map[string]ScriptChunk{"list": {URL: "/static/js/app.list.1.js"}, "form": {URL: "/static/js/app.form.2.js"}}}
+	data := map[string]interface{}{"scripts": "<script src=\"/static/js/app.3.js\"></script>\n"}
+	a.addScriptChunks(data, []string{"list", "main"})
+	want := "<script type=\"application/json\" id=\"component-chunks\">{\"loaded\":[\"list\"],\"urls\":{\"form\":\"/static/js/app.form.2.js\",\"list\":\"/static/js/app.list.1.js\"}}</script>\n" +
+		"<script src=\"/static/js/app.3.js\"></script>\n" +
+		"<script type=\"module\" src=\"/static/js/app.list.1.js\"></script>\n"
+	if data["scripts"] != want {
+		t.Errorf("scripts:\n got %s\nwant %s", data["scripts"], want)
+	}
+	if data["preload"] != "<link rel=\"modulepreload\" href=\"/static/js/app.list.1.js\">\n" {
+		t.Errorf("preload: %s", data["preload"])
+	}
+	if strings.Contains(chunkLoader, "var m") {
+		t.Error("chunk loader declares m")
+	}
+}
@@ -1,10 +1,12 @@
 package components
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/jmu0/dbAPI/db"
 	"github.com/jmu0/templates"
@@ -17,7 +19,27 @@ type Component struct {
 	TemplateManager templates.TemplateManager
 	StyleFiles      []string
 	JsFiles         []string
-	DataFunc        DataFunc
+	DataFunc        DataFunc    //used when DataFuncCtx is nil
+	DataFuncCtx     DataFuncCtx //data func with request context
+	Data            DataSource  //from component.yml
+	Auth            bool        //data only for authenticated users, from api route used as data source
+	Roles           []string    //data only for users with one of roles, from api route used as data source
+	Claims          []string    //data only when conditions on claims are true, from api route used as data source
+}
+
+//dataPermitted checks principal against access of api route used as data source
+func (c *Component) dataPermitted(p *Principal) bool {
+	if c.Auth == false && len(c.Roles) == 0 && len(c.Claims) == 0 {
+		return true
+	}
+	if p == nil {
+		return false
+	}
+	ok, err := Permitted(c.Roles, c.Claims, p.Claims)
+	if err != nil {
+		log.Println("ERROR:", err)
+	}
+	return ok
 }
 
 //OldName returns name from path
@@ -28,21 +50,41 @@ func (c *Component) OldName() string {
 
 //GetData gets data. keys from url path
 func (c *Component) GetData(args map[string]string, conn db.Conn) ([]map[string]interface{}, error) {
+	return c.GetDataCtx(context.Background(), &DataRequest{Args: args}, conn)
+}
+
+//GetDataCtx gets data with request context. keys from url path when not set
+func (c *Component) GetDataCtx(ctx context.Context, req *DataRequest, conn db.Conn) ([]map[string]interface{}, error) {
 	var ret = make([]map[string]interface{}, 0)
-	if c.DataFunc == nil {
+	f := c.DataFuncCtx
+	if f == nil && c.DataFunc != nil {
+		f = AdaptDataFunc(c.DataFunc)
+	}
+	if f == nil {
 		return ret, nil //errors.New("No DataFunc for component: " + c.Name)
 	}
-	var param string
-	spl := strings.Split(args["path"], "/")
-	keys := strings.Split(spl[len(spl)-1], ":")
-	params := make([]string, 0)
-	for i := range keys {
-		param = db.Escape(strings.TrimSpace(keys[i]))
-		if len(param) > 0 {
-			params = append(params, param)
+	if req.Keys == nil {
+		var param string
+		spl := strings.Split(req.Args["path"], "/")
+		keys := strings.Split(spl[len(spl)-1], ":")
+		req.Keys = make([]string, 0)
+		for i := range keys {
+			param = db.Escape(strings.TrimSpace(keys[i]))
+			if len(param) > 0 {
+				req.Keys = append(req.Keys, param)
+			}
 		}
 	}
-	return c.DataFunc(args, params, conn)
+	return f(ctx, req, conn)
+}
+
+//dataRequest returns request data for data func of component route
+func dataRequest(r *http.Request, args map[string]string) *DataRequest {
+	req := &DataRequest{Request: r, Args: args}
+	if p, err := Authenticate(r); err == nil {
+		req.Principal = p
+	}
+	return req
 }
 
 //Render renders the component
@@ -51,18 +93,26 @@ func (c *Component) Render(templateName string, args map[string]string, data map
 	if err != nil {
 		//get first template in cache if not found
 		for _, first := range c.TemplateManager.Cache {
-			first.Data = data
-			return c.TemplateManager.Render(first, args["locale"])
+			return c.render(first, data, args["locale"])
 		}
 		return "", err
 	}
-	tmpl.Data = data
-	return c.TemplateManager.Render(tmpl, args["locale"])
+	return c.render(tmpl, data, args["locale"])
+}
+
+//render renders template and translates {{t:key}} markers
+func (c *Component) render(tmpl *templates.Template, data map[string]interface{}, locale string) (string, error) {
+	html, err := render(&c.TemplateManager, tmpl, data, locale)
+	if err != nil {
+		return html, err
+	}
+	return translateMarkers(&c.TemplateManager, html, c.Name, locale), nil
 }
 
 //Render renders component (prevent closure in loop over templates)
 func handleFunc(c Component, templateName string, conn db.Conn) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		r = authenticateRequest(r)
 		var html, itemhtml string
 		spl := strings.Split(r.URL.Path, "/")
 		if spl[len(spl)-1] == templateName {
@@ -70,7 +120,11 @@ func handleFunc(c Component, templateName string, conn db.Conn) func(w http.Resp
 			http.NotFound(w, r)
 			return
 		}
-		data, err := c.GetData(GetRequestArgs(r), conn)
+		if authorize(w, r, c.Auth, c.Roles, c.Claims) == false {
+			return
+		}
+		args := GetRequestArgs(r)
+		data, err := c.GetDataCtx(r.Context(), dataRequest(r, args), conn)
 		if err != nil {
 
 			log.Println("Query error:", err)
@@ -84,10 +138,11 @@ func handleFunc(c Component, templateName string, conn db.Conn) func(w http.Resp
 			return
 		}
 		if len(data) <= 1 {
+			d := make(map[string]interface{})
 			if len(data) == 1 {
-				tmpl.Data = data[0]
+				d = data[0]
 			}
-			html, err = c.TemplateManager.Render(tmpl, "nl")
+			html, err = c.render(tmpl, d, args["locale"])
 			if err != nil {
 				log.Println("Error:", err)
 				http.NotFound(w, r)
@@ -95,8 +150,7 @@ func handleFunc(c Component, templateName string, conn db.Conn) func(w http.Resp
 			}
 		} else if len(data) > 1 {
 			for i := range data {
-				tmpl.Data = data[i]
-				itemhtml, err = c.TemplateManager.Render(tmpl, "nl")
+				itemhtml, err = c.render(tmpl, data[i], args["locale"])
 				if err != nil {
 					log.Println("Error:", err)
 					http.NotFound(w, r)
@@ -106,14 +160,19 @@ func handleFunc(c Component, templateName string, conn db.Conn) func(w http.Resp
 			}
 		}
 		log.Println("Serving ", r.URL.Path)
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		w.Write([]byte(html))
+		varyLocale(w)
+		varyAuth(w, r)
+		writeResponse(w, r, "text/html; charset=utf-8", []byte(html), "", time.Time{})
 	}
 }
 
 func handleFuncData(c Component, conn db.Conn) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		data, err := c.GetData(GetRequestArgs(r), conn)
+		r = authenticateRequest(r)
+		if authorize(w, r, c.Auth, c.Roles, c.Claims) == false {
+			return
+		}
+		data, err := c.GetDataCtx(r.Context(), dataRequest(r, GetRequestArgs(r)), conn)
 		if err != nil {
 			log.Println("Error handle data:", err)
 			http.NotFound(w, r)
@@ -126,8 +185,9 @@ func handleFuncData(c Component, conn db.Conn) func(w http.ResponseWriter, r *ht
 			return
 		}
 		log.Println("Serving data:", r.URL.Path)
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		w.Write(bytes)
+		varyLocale(w)
+		varyAuth(w, r)
+		writeResponse(w, r, "application/json; charset=utf-8", bytes, "", time.Time{})
 	}
 }
 
@@ -152,7 +212,7 @@ func handleFuncTemplate(c Component, name string) func(w http.ResponseWriter, r
 }
 
 //AddRoutesComponent adds routes for html endpoints
-func (c *Component) AddRoutesComponent(mx *http.ServeMux, conn db.Conn) {
+func (c *Component) AddRoutesComponent(mx *Router, conn db.Conn) {
 	var route string
 	split := strings.Split(c.Name, ".")
 	for name := range c.TemplateManager.GetTemplates() {
@@ -162,26 +222,36 @@ func (c *Component) AddRoutesComponent(mx *http.ServeMux, conn db.Conn) {
 			route = name
 		}
 		log.Println("Adding route for component: /component/" + route + "/")
-		mx.HandleFunc("/component/"+route+"/", handleFunc(*c, name, conn))
+		mx.HandleRoute(RouteInfo{Pattern: "/component/" + route + "/", Methods: methodsGet, Type: "component", Auth: c.Auth || len(c.Roles) > 0 || len(c.Claims) > 0, Roles: c.Roles, Component: c.Name, Source: c.Path, Handler: http.HandlerFunc(handleFunc(*c, name, conn))})
 		if len(split) > 1 {
 			route = strings.Join(split[:len(split)-1], ".") + "." + name
 		} else {
 			route = name
 		}
 		log.Println("Adding route for template: /static/templates/" + route + ".html")
-		mx.HandleFunc("/static/templates/"+route+".html", handleFuncTemplate(*c, name))
+		mx.HandleRoute(RouteInfo{Pattern: "/static/templates/" + route + ".html", Methods: methodsGet, Type: "template", Component: c.Name, Source: c.Path + "/" + name + ".html", Handler: http.HandlerFunc(handleFuncTemplate(*c, name))})
+	}
+}
+
+//AddRoutesData adds route for json data when component has a data source: /data/[name]/[key1]:[key2]
+func (c *Component) AddRoutesData(mx *Router, conn db.Conn) {
+	if c.DataFuncCtx == nil && c.DataFunc == nil {
+		return
 	}
+	route := "/data/" + strings.Replace(c.Name, ".", "/", -1) + "/"
+	log.Println("Adding route for data: " + route)
+	mx.HandleRoute(RouteInfo{Pattern: route, Methods: methodsGet, Type: "data", Auth: c.Auth || len(c.Roles) > 0 || len(c.Claims) > 0, Roles: c.Roles, Component: c.Name, Source: c.Path, Handler: http.HandlerFunc(handleFuncData(*c, conn))})
 }
 
 //AddRoutesScripts adds Routes for js files
-func (c *Component) AddRoutesScripts(mx *http.ServeMux, rootPath string) {
+func (c *Component) AddRoutesScripts(mx *Router, rootPath string) {
 	if len(c.JsFiles) > 0 {
 		var route string
 		var i int
 		for i = 0; i < len(c.JsFiles); i++ {
 			route = "/" + strings.Replace(c.JsFiles[i], rootPath, "", -1)
 			log.Println("Adding route for script:" + route)
-			mx.HandleFunc(route, handleFuncScript(c.JsFiles[i]))
+			mx.HandleRoute(RouteInfo{Pattern: route, Methods: methodsGet, Type: "script", Component: c.Name, Source: c.JsFiles[i], Handler: http.HandlerFunc(handleFuncScript(c.JsFiles[i]))})
 		}
 	}
 }
@@ -0,0 +1,199 @@
+package components
+
+import (
+	"strings"
+)
+
+//scopedAtRules at-rules with nested style rules, selectors inside are scoped
+var scopedAtRules = map[string]bool{"media": true, "supports": true, "document": true, "layer": true, "container": true}
+
+//scopeSelector returns selector for component tag emitted by Part.Render, ex: nested.example1 => nested\.example1
+func scopeSelector(component string) string {
+	return strings.Replace(strings.ToLower(component), ".", "\\.", -1)
+}
+
+//scopeCSS prefixes selectors of style rules with scope. :host is replaced with scope, :host(.x) with scope.x,
+//@keyframes, @font-face and other at-rules are not changed
+func scopeCSS(css, scope string) string {
+	var out strings.Builder
+	i := 0
+	for i < len(css) {
+		j := skipCSSSpace(css, i)
+		out.WriteString(css[i:j])
+		i = j
+		if i >= len(css) {
+			break
+		}
+		if css[i] == '}' {
+			//end of @media block
+			out.WriteByte('}')
+			i++
+			continue
+		}
+		end := findCSS(css, i, "{;")
+		if end < 0 {
+			out.WriteString(css[i:])
+			break
+		}
+		if css[i] == '@' {
+			out.WriteString(css[i : end+1])
+			k := i + 1
+			for k < end && (css[k] == '-' || (css[k] >= 'a' && css[k] <= 'z') || (css[k] >= 'A' && css[k] <= 'Z')) {
+				k++
+			}
+			name := strings.ToLower(css[i+1 : k])
+			if css[end] == '{' && !scopedAtRules[name] {
+				k := matchBrace(css, end)
+				out.WriteString(css[end+1 : k+1])
+				end = k
+			}
+			i = end + 1
+			continue
+		}
+		if css[end] == ';' {
+			//declaration outside rule
+			out.WriteString(css[i : end+1])
+			i = end + 1
+			continue
+		}
+		out.WriteString(scopeSelectors(css[i:end], scope))
+		k := matchBrace(css, end)
+		out.WriteString(css[end : k+1])
+		i = k + 1
+	}
+	return out.String()
+}
+
+//scopeSelectors prefixes comma separated selectors with scope
+func scopeSelectors(selectors, scope string) string {
+	var ret []string
+	depth := 0
+	start := 0
+	var quote byte
+	for i := 0; i <= len(selectors); i++ {
+		if i < len(selectors) {
+			c := selectors[i]
+			switch {
+			case quote != 0:
+				if c == '\\' {
+					i++
+				} else if c == quote {
+					quote = 0
+				}
+				continue
+			case c == '"' || c == '\'':
+				quote = c
+				continue
+			case c == '(' || c == '[':
+				depth++
+				continue
+			case c == ')' || c == ']':
+				depth--
+				continue
+			case c != ',' || depth > 0:
+				continue
+			}
+		}
+		sel := strings.TrimSpace(selectors[start:i])
+		start = i + 1
+		switch {
+		case sel == "":
+		case strings.HasPrefix(sel, ":host("):
+			//:host(.active) p => scope.active p
+			depth := 0
+			for k := 5; k < len(sel); k++ {
+				if sel[k] == '(' {
+					depth++
+				} else if sel[k] == ')' {
+					depth--
+					if depth == 0 {
+						sel = scope + sel[6:k] + sel[k+1:]
+						break
+					}
+				}
+			}
+		case strings.HasPrefix(sel, ":host") && (len(sel) == 5 || !isIdentChar(rune(sel[5])) && sel[5] != '-'):
+			sel = scope + sel[5:]
+		case sel == scope || strings.HasPrefix(sel, scope+" ") || strings.HasPrefix(sel, scope+">"):
+		default:
+			sel = scope + " " + sel
+		}
+		ret = append(ret, sel)
+	}
+	return strings.Join(ret, ",")
+}
+
+//skipCSSSpace returns offset after whitespace and comments
+func skipCSSSpace(css string, i int) int {
+	for i < len(css) {
+		switch {
+		case css[i] == ' ' || css[i] == '\t' || css[i] == '\n' || css[i] == '\r':
+			i++
+		case strings.HasPrefix(css[i:], "/*"):
+			if k := strings.Index(css[i+2:], "*/"); k >= 0 {
+				i += k + 4
+			} else {
+				i = len(css)
+			}
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+//findCSS returns offset of first of chars outside strings and comments, -1 if not found
+func findCSS(css string, i int, chars string) int {
+	for i < len(css) {
+		c := css[i]
+		switch {
+		case c == '"' || c == '\'':
+			i = skipCSSString(css, i)
+		case strings.HasPrefix(css[i:], "/*"):
+			if k := strings.Index(css[i+2:], "*/"); k >= 0 {
+				i += k + 4
+			} else {
+				return -1
+			}
+		case strings.IndexByte(chars, c) >= 0:
+			return i
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+//matchBrace returns offset of } closing { at i, last offset when not closed
+func matchBrace(css string, i int) int {
+	depth := 0
+	for i < len(css) {
+		k := findCSS(css, i, "{}")
+		if k < 0 {
+			return len(css) - 1
+		}
+		if css[k] == '{' {
+			depth++
+		} else {
+			depth--
+			if depth == 0 {
+				return k
+			}
+		}
+		i = k + 1
+	}
+	return len(css) - 1
+}
+
+//skipCSSString returns offset after quoted string at i
+func skipCSSString(css string, i int) int {
+	quote := css[i]
+	for i++; i < len(css); i++ {
+		if css[i] == '\\' {
+			i++
+		} else if css[i] == quote {
+			return i + 1
+		}
+	}
+	return i
+}
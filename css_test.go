@@ -0,0 +1,30 @@
+package components
+
+import "testing"
+
+func TestScopeCSS(t *testing.T) {
+	scope := scopeSelector("nested.Example1")
+	if scope != "nested\\.example1" {
+		t.Fatalf("scopeSelector: %s", scope)
+	}
+	tests := []struct {
+		css  string
+		want string
+	}{
+		{"h2 { color: red; }", "nested\\.example1 h2{ color: red; }"},
+		{"h2, p.a > span { color: red; }", "nested\\.example1 h2,nested\\.example1 p.a > span{ color: red; }"},
+		{":host { display: block; } :host(.x) p {} :host(:not(.y)) {}", "nested\\.example1{ display: block; } nested\\.example1.x p{} nested\\.example1:not(.y){}"},
+		{"a[title=\"a,b\"], b:not(.c, .d) {}", "nested\\.example1 a[title=\"a,b\"],nested\\.example1 b:not(.c, .d){}"},
+		{"@media (max-width: 600px) { h2 { color: red; } }", "@media (max-width: 600px) { nested\\.example1 h2{ color: red; } }"},
+		{"@keyframes spin { from { top: 0; } to { top: 1px; } }", "@keyframes spin { from { top: 0; } to { top: 1px; } }"},
+		{"@font-face { font-family: x; }", "@font-face { font-family: x; }"},
+		{"@import url(\"a.css\");\nh2 {}", "@import url(\"a.css\");\nnested\\.example1 h2{}"},
+		{"/* h2 { } */ p { content: \"}\"; }", "/* h2 { } */ nested\\.example1 p{ content: \"}\"; }"},
+		{"nested\\.example1 h2 {}", "nested\\.example1 h2{}"},
+	}
+	for _, test := range tests {
+		if got := scopeCSS(test.css, scope); got != test.want {
+			t.Errorf("%s:\n got %s\nwant %s", test.css, got, test.want)
+		}
+	}
+}
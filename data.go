@@ -0,0 +1,250 @@
+package components
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jmu0/dbAPI/db"
+	"gopkg.in/yaml.v2"
+)
+
+//DataFuncCtx function for getting data for component with request context.
+//ctx is cancelled when the client disconnects or the page timeout expires
+type DataFuncCtx func(ctx context.Context, req *DataRequest, conn db.Conn) ([]map[string]interface{}, error)
+
+//DataRequest request data for DataFuncCtx
+type DataRequest struct {
+	Request   *http.Request          //nil when rendered without request
+	Principal *Principal             //nil when not authenticated
+	Args      map[string]string      //path, locale, claims and path parameters
+	Keys      []string               //keys from url path: /component/[name]/[key1]:[key2]
+	Params    map[string]interface{} //typed path parameters from page route, ex: {maat:int}
+}
+
+//AdaptDataFunc adapts DataFunc to DataFuncCtx
+func AdaptDataFunc(f DataFunc) DataFuncCtx {
+	return func(ctx context.Context, req *DataRequest, conn db.Conn) ([]map[string]interface{}, error) {
+		return f(req.Args, req.Keys, conn)
+	}
+}
+
+//ComponentConfig component.yml in component folder
+type ComponentConfig struct {
+	Data DataSource `yaml:"data"`
+}
+
+//DataSource data for component: sql, api route, registered DataFunc or json fixture
+type DataSource struct {
+	SQL     string   `yaml:"sql"`     //query, parameters from source (page path parameters, claims, query string) or keys from url path
+	Params  []Param  `yaml:"params"`  //declared parameters for sql
+	API     string   `yaml:"api"`     //query route from api.yml
+	Func    string   `yaml:"func"`    //name in App.DataFuncsCtx or App.DataFuncs
+	Fixture string   `yaml:"fixture"` //json file in component folder
+	TTL     string   `yaml:"ttl"`     //cache data, ex: 30s. key includes path, locale, claims and path parameters
+	Tables  []string `yaml:"tables"`  //tables cached data depends on, default: tables in sql
+}
+
+//empty checks if no data source is declared
+func (d *DataSource) empty() bool {
+	return d.SQL == "" && d.API == "" && d.Func == "" && d.Fixture == ""
+}
+
+//loadDataSource loads data source from component.yml
+func loadDataSource(path string) (DataSource, error) {
+	var cfg ComponentConfig
+	if _, err := os.Stat(path + "/component.yml"); err == nil {
+		yml, err := ioutil.ReadFile(path + "/component.yml")
+		if err != nil {
+			return cfg.Data, err
+		}
+		err = yaml.Unmarshal(yml, &cfg)
+		if err != nil {
+			return cfg.Data, errors.New(path + "/component.yml: " + err.Error())
+		}
+	}
+	return cfg.Data, nil
+}
+
+//stripSQLComments removes -- comment lines
+func stripSQLComments(sql string) string {
+	var lines []string
+	for _, line := range strings.Split(sql, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") == false {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+//dataFunc returns data func for data source of component. api routes are resolved by resolveAPIData.
+//without data source: data func registered with component name, or get.sql
+func (a *App) dataFunc(c Component) (DataFuncCtx, error) {
+	d := c.Data
+	ttl, err := parseTTL(d.TTL)
+	if err != nil {
+		return nil, errors.New(c.Path + "/component.yml: ttl: " + err.Error())
+	}
+	cached := func(f DataFuncCtx) DataFuncCtx {
+		if ttl > 0 {
+			return cachedDataFunc(c.Name, ttl, d.Tables, f)
+		}
+		return f
+	}
+	switch {
+	case d.Func != "":
+		if f, ok := a.DataFuncsCtx[d.Func]; ok {
+			return cached(f), nil
+		}
+		if f, ok := a.DataFuncs[d.Func]; ok {
+			return cached(AdaptDataFunc(f)), nil
+		}
+		return nil, errors.New("DataFunc not found for component " + c.Name + ": " + d.Func)
+	case d.SQL != "":
+		return sqlDataFunc(Route{Route: c.Name, Type: "query", SQL: d.SQL, Params: d.Params, Tables: d.Tables, TTL: d.TTL})
+	case d.Fixture != "":
+		return fixtureDataFunc(c.Path + "/" + d.Fixture)
+	case d.API != "":
+		return nil, nil
+	}
+	if f, ok := a.DataFuncsCtx[c.Name]; ok {
+		return cached(f), nil
+	}
+	if f, ok := a.DataFuncs[c.Name]; ok {
+		return cached(AdaptDataFunc(f)), nil
+	}
+	if sql, err := ioutil.ReadFile(c.Path + "/get.sql"); err == nil {
+		return sqlDataFunc(Route{Route: c.Name, Type: "query", SQL: stripSQLComments(string(sql)), Tables: d.Tables, TTL: d.TTL})
+	}
+	return nil, nil
+}
+
+//resolveAPIData sets data func for components with api route as data source, after all api.yml files are loaded
+func (a *App) resolveAPIData() error {
+	for name, c := range a.Components {
+		if c.Data.API == "" || c.DataFuncCtx != nil {
+			continue
+		}
+		rt, ok := a.routes[c.Data.API]
+		if !ok || rt.Type != "query" {
+			return errors.New("Query route not found for component " + name + ": " + c.Data.API)
+		}
+		route := *rt
+		if c.Data.TTL != "" {
+			route.TTL = c.Data.TTL
+		}
+		if len(c.Data.Tables) > 0 {
+			route.Tables = c.Data.Tables
+		}
+		f, err := sqlDataFunc(route)
+		if err != nil {
+			return err
+		}
+		c.DataFuncCtx = f
+		c.Auth, c.Roles, c.Claims = route.Auth, route.Roles, route.Claims
+		a.Components[name] = c
+	}
+	return nil
+}
+
+//sqlDataFunc returns data func for query. parameters are taken from their source: path parameters of page (path),
+//claims of principal (claim) or query string (query). others from keys in url path
+func sqlDataFunc(route Route) (DataFuncCtx, error) {
+	_, names, err := compileSQL(route.SQL)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range route.Params {
+		if err := p.check(); err != nil {
+			return nil, errors.New(route.Route + ": " + err.Error())
+		}
+		if strings.ToLower(p.Source) == "body" {
+			return nil, errors.New("Parameter source body not supported for component data: " + route.Route + " " + p.Name)
+		}
+	}
+	return func(ctx context.Context, req *DataRequest, conn db.Conn) ([]map[string]interface{}, error) {
+		values, err := route.dataValues(names, req)
+		if err != nil {
+			return nil, err
+		}
+		return route.getData(ctx, req.Args["path"], values, conn)
+	}, nil
+}
+
+//dataValues gets values of named and declared parameters from their source in data request
+func (r *Route) dataValues(names []string, req *DataRequest) (map[string]string, error) {
+	values := make(map[string]string)
+	for i := range names {
+		if names[i] == "" && i >= len(r.Params) {
+			continue
+		}
+		name := r.paramName(names, i)
+		p := r.param(name)
+		switch strings.ToLower(p.Source) {
+		case "", "path":
+			if v, ok := req.Params[p.key()]; ok {
+				values[name] = paramString(p, v)
+			}
+		case "claim":
+			if req.Principal != nil {
+				if v, ok := req.Principal.Claims[p.key()]; ok {
+					values[name] = v
+				}
+			}
+		case "query":
+			if req.Request != nil {
+				if v, ok := req.Request.URL.Query()[p.key()]; ok {
+					values[name] = strings.Join(v, ",")
+				}
+			}
+		default:
+			return nil, errors.New("Unknown parameter source: " + p.Source)
+		}
+	}
+	return values, nil
+}
+
+//paramString formats typed path parameter as value for Coerce
+func paramString(p Param, v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case time.Time:
+		if strings.ToLower(p.Type) == "date" {
+			return val.Format("2006-01-02")
+		}
+		return val.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+//fixtureDataFunc returns data func for json file with object or array of objects
+func fixtureDataFunc(file string) (DataFuncCtx, error) {
+	bytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var data []map[string]interface{}
+	if strings.HasPrefix(strings.TrimSpace(string(bytes)), "{") {
+		var one map[string]interface{}
+		err = json.Unmarshal(bytes, &one)
+		data = append(data, one)
+	} else {
+		err = json.Unmarshal(bytes, &data)
+	}
+	if err != nil {
+		return nil, errors.New(file + ": " + err.Error())
+	}
+	log.Println("Loading fixture:", file)
+	return func(ctx context.Context, req *DataRequest, conn db.Conn) ([]map[string]interface{}, error) {
+		return copyRows(data), nil
+	}, nil
+}
@@ -0,0 +1,178 @@
+package components
+
+import (
+	"bytes"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+//serverEncodings content encodings in order of preference
+var serverEncodings = []string{"br", "gzip"}
+
+//encodingExt extension of precompressed static files
+var encodingExt = map[string]string{"br": ".br", "gzip": ".gz"}
+
+//Encoded content with precompressed gzip and brotli variants
+type Encoded struct {
+	Identity []byte
+	Gzip     []byte
+	Brotli   []byte
+	etag     string
+}
+
+//NewEncoded compresses content with gzip and brotli. variant is left empty when compressing fails
+func NewEncoded(content []byte) Encoded {
+	e := Encoded{Identity: content, etag: computeETag(content)}
+	gz, err := Compress(content)
+	if err != nil {
+		log.Println("Error compressing (gzip):", err)
+	} else {
+		e.Gzip = gz
+	}
+	br, err := CompressBrotli(content)
+	if err != nil {
+		log.Println("Error compressing (brotli):", err)
+	} else {
+		e.Brotli = br
+	}
+	return e
+}
+
+//CompressBrotli compresses a []byte with brotli
+func CompressBrotli(inp []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	bw := brotli.NewWriterLevel(&buf, brotli.BestCompression)
+	_, err := bw.Write(inp)
+	if err != nil {
+		return inp, err
+	}
+	if err := bw.Close(); err != nil {
+		return inp, err
+	}
+	return buf.Bytes(), nil
+}
+
+//variant returns content for encoding, nil when not available
+func (e *Encoded) variant(encoding string) []byte {
+	switch encoding {
+	case "br":
+		return e.Brotli
+	case "gzip":
+		return e.Gzip
+	case "identity":
+		return e.Identity
+	}
+	return nil
+}
+
+//negotiate returns encoding, content and etag for Accept-Encoding header
+func (e *Encoded) negotiate(acceptEncoding string) (string, []byte, string) {
+	etag := e.etag
+	if etag == "" {
+		etag = computeETag(e.Identity)
+	}
+	for _, enc := range acceptedEncodings(acceptEncoding) {
+		if content := e.variant(enc); content != nil {
+			if enc != "identity" {
+				//representations with different encodings need different strong etags
+				etag = strings.TrimSuffix(etag, "\"") + "-" + enc + "\""
+			}
+			return enc, content, etag
+		}
+	}
+	return "identity", e.Identity, etag
+}
+
+//write writes variant for Accept-Encoding of request
+func (e *Encoded) write(w http.ResponseWriter, r *http.Request, contentType string, modified time.Time) {
+	addVary(w, "Accept-Encoding")
+	enc, content, etag := e.negotiate(r.Header.Get("Accept-Encoding"))
+	if enc != "identity" {
+		w.Header().Set("Content-Encoding", enc)
+	}
+	writeResponse(w, r, contentType, content, etag, modified)
+}
+
+//acceptedEncodings returns encodings accepted by client, most preferred first. identity is always last
+func acceptedEncodings(header string) []string {
+	q := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		enc := strings.ToLower(strings.TrimSpace(fields[0]))
+		if enc == "" {
+			continue
+		}
+		q[enc] = 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q[enc] = v
+				}
+			}
+		}
+	}
+	var ret []string
+	for _, enc := range serverEncodings {
+		v, ok := q[enc]
+		if !ok {
+			v, ok = q["*"]
+		}
+		if ok && v > 0 {
+			ret = append(ret, enc)
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return qValue(q, ret[i]) > qValue(q, ret[j])
+	})
+	return append(ret, "identity")
+}
+
+//qValue returns quality for encoding, * for encodings not listed
+func qValue(q map[string]float64, enc string) float64 {
+	if v, ok := q[enc]; ok {
+		return v
+	}
+	return q["*"]
+}
+
+//serveStatic serves files in root, precompressed .br or .gz sibling when client accepts encoding
+func serveStatic(root string) http.Handler {
+	dir := http.Dir(root)
+	fs := http.FileServer(dir)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addVary(w, "Accept-Encoding")
+		name := path.Clean("/" + r.URL.Path)
+		for _, enc := range acceptedEncodings(r.Header.Get("Accept-Encoding")) {
+			if enc == "identity" {
+				break
+			}
+			f, err := dir.Open(name + encodingExt[enc])
+			if err != nil {
+				continue
+			}
+			defer f.Close()
+			stat, err := f.Stat()
+			if err != nil || stat.IsDir() {
+				continue
+			}
+			ctype := mime.TypeByExtension(path.Ext(name))
+			if ctype == "" {
+				ctype = "application/octet-stream"
+			}
+			w.Header().Set("Content-Type", ctype)
+			w.Header().Set("Content-Encoding", enc)
+			http.ServeContent(w, r, name, stat.ModTime(), f)
+			return
+		}
+		fs.ServeHTTP(w, r)
+	})
+}
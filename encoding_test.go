@@ -0,0 +1,28 @@
+package components
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAcceptedEncodings(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"", []string{"identity"}},
+		{"gzip", []string{"gzip", "identity"}},
+		{"gzip, deflate, br", []string{"br", "gzip", "identity"}},
+		{"br;q=0.5, gzip", []string{"gzip", "br", "identity"}},
+		{"br;q=0, gzip;q=0.8", []string{"gzip", "identity"}},
+		{"*", []string{"br", "gzip", "identity"}},
+		{"*;q=0.1, gzip;q=0.5", []string{"gzip", "br", "identity"}},
+		{"GZIP ; q=1.0", []string{"gzip", "identity"}},
+		{"*;q=0", []string{"identity"}},
+	}
+	for _, test := range tests {
+		if got := acceptedEncodings(test.header); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.header, got, test.want)
+		}
+	}
+}
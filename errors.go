@@ -0,0 +1,94 @@
+package components
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+)
+
+//renderStatus renders component inside main layout with status code.
+//falls back to plain text response if component doesn't exist or fails to render
+func (a *App) renderStatus(w http.ResponseWriter, r *http.Request, status int, name string, data map[string]interface{}) {
+	if cmp, ok := a.Components[name]; ok {
+		rc := NewRenderContext(r)
+		data["status"] = status
+		html, err := cmp.Render("", rc.Args, data)
+		if err == nil {
+			cmpName := strings.ToLower(name)
+			rc.Content = "<" + cmpName + " data-component='" + cmpName + "' rendered>" + html + "</" + cmpName + ">"
+			rc.Components = []string{name}
+			html, err = a.renderMain(rc, "")
+		}
+		if err == nil {
+			log.Println("Rendering", name, status)
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(status)
+			w.Write([]byte(html))
+			return
+		}
+		log.Println("ERROR rendering", name+":", err)
+	}
+	http.Error(w, http.StatusText(status), status)
+}
+
+//renderNotFound renders not found component with status 404. values are escaped, templates insert them as html
+func (a *App) renderNotFound(w http.ResponseWriter, r *http.Request) {
+	log.Println("Not found:", r.URL.Path)
+	data := make(map[string]interface{})
+	data["uri"] = html.EscapeString(r.URL.Path)
+	a.renderStatus(w, r, http.StatusNotFound, a.NotFound, data)
+}
+
+//panicError error for recovered panic, with stack of goroutine that panicked
+type panicError struct {
+	msg   string
+	stack []byte
+}
+
+func (e *panicError) Error() string {
+	return e.msg
+}
+
+//recovered returns error with stack for recovered panic, call in deferred func that recovered
+func recovered(msg string, rec interface{}) error {
+	return &panicError{msg: fmt.Sprintf("%s: %v", msg, rec), stack: debug.Stack()}
+}
+
+//renderError logs error with error id and renders error component with status 500.
+//in debug mode the error and for panics the stack are added to the page
+func (a *App) renderError(w http.ResponseWriter, r *http.Request, err error) {
+	a.renderErrorStatus(w, r, http.StatusInternalServerError, err)
+}
+
+//renderErrorStatus logs error with error id and renders error component with status
+func (a *App) renderErrorStatus(w http.ResponseWriter, r *http.Request, status int, err error) {
+	id := errorID()
+	log.Println("ERROR", id+":", err)
+	data := make(map[string]interface{})
+	data["uri"] = html.EscapeString(r.URL.Path)
+	data["error_id"] = id
+	data["error"] = ""
+	data["stack"] = ""
+	if a.Debug == true {
+		data["error"] = html.EscapeString(err.Error())
+		if pe, ok := err.(*panicError); ok {
+			data["stack"] = html.EscapeString(string(pe.stack))
+		}
+	}
+	a.renderStatus(w, r, status, a.Error, data)
+}
+
+//errorID returns random id for finding error in log
+func errorID() string {
+	b := make([]byte, 6)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
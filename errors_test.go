@@ -0,0 +1,31 @@
+package components
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jmu0/templates"
+)
+
+func TestRenderStatusEscapes(t *testing.T) {
+	a := &App{NotFound: "not_found", Error: "error", Debug: true}
+	a.TemplateManager.Cache = map[string]*templates.Template{"main": {HTML: "<main>${{content}}</main>", Data: map[string]interface{}{}}}
+	a.Components = map[string]Component{
+		"not_found": {Name: "not_found", TemplateManager: templates.TemplateManager{Cache: map[string]*templates.Template{"not_found": {HTML: "<p>${{uri}}</p>"}}}},
+		"error":     {Name: "error", TemplateManager: templates.TemplateManager{Cache: map[string]*templates.Template{"error": {HTML: "<p>${{uri}}</p><pre>${{error}}</pre>"}}}},
+	}
+	r := WithPrincipal(httptest.NewRequest(http.MethodGet, "/%3Cimg%20src=x%20onerror=alert(1)%3E", nil), nil)
+	w := httptest.NewRecorder()
+	a.renderNotFound(w, r)
+	if w.Code != http.StatusNotFound || strings.Contains(w.Body.String(), "<img") {
+		t.Errorf("not found: %d %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	a.renderError(w, r, errors.New("<script>"))
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "<img") || strings.Contains(w.Body.String(), "<script>") {
+		t.Errorf("error: %d %s", w.Code, w.Body.String())
+	}
+}
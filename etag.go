@@ -0,0 +1,89 @@
+package components
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//computeETag returns strong etag for body
+func computeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "\"" + hex.EncodeToString(sum[:16]) + "\""
+}
+
+//writeResponse writes body with etag (computed when empty) and Last-Modified when modified is set.
+//GET and HEAD requests get 304 when If-None-Match or If-Modified-Since matches
+func writeResponse(w http.ResponseWriter, r *http.Request, contentType string, body []byte, etag string, modified time.Time) {
+	if etag == "" {
+		etag = computeETag(body)
+	}
+	w.Header().Set("ETag", etag)
+	if !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if notModified(r, etag, modified) {
+		w.Header().Del("Content-Encoding")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
+
+//notModified checks conditional GET headers. If-Modified-Since is ignored when If-None-Match is set
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !modified.IsZero() {
+		t, err := http.ParseTime(ims)
+		return err == nil && !modified.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
+//addVary adds headers to Vary header of response
+func addVary(w http.ResponseWriter, headers ...string) {
+	vary := w.Header().Get("Vary")
+	for _, h := range headers {
+		found := false
+		for _, v := range strings.Split(vary, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), h) {
+				found = true
+			}
+		}
+		if found == false {
+			if vary != "" {
+				vary += ", "
+			}
+			vary += h
+		}
+	}
+	w.Header().Set("Vary", vary)
+}
+
+//varyLocale response depends on locale: Accept-Language or locale cookie
+func varyLocale(w http.ResponseWriter) {
+	addVary(w, "Accept-Language", "Cookie")
+}
+
+//varyAuth response depends on principal: Authorization header or token/session cookie.
+//responses for authenticated users are not stored by shared caches
+func varyAuth(w http.ResponseWriter, r *http.Request) {
+	addVary(w, "Authorization", "Cookie")
+	if _, err := Authenticate(r); err == nil {
+		w.Header().Set("Cache-Control", "private")
+	}
+}
@@ -0,0 +1,68 @@
+package components
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWriteResponseETag(t *testing.T) {
+	body := []byte("<p>page</p>")
+	etag := computeETag(body)
+	w := httptest.NewRecorder()
+	writeResponse(w, httptest.NewRequest(http.MethodGet, "/", nil), "text/html", body, "", time.Time{})
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != etag || w.Body.String() != string(body) {
+		t.Fatalf("first response: %d %s %s", w.Code, w.Header().Get("ETag"), w.Body.String())
+	}
+	for _, inm := range []string{etag, "W/" + etag, "\"other\", " + etag, "*"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("If-None-Match", inm)
+		w := httptest.NewRecorder()
+		writeResponse(w, r, "text/html", body, "", time.Time{})
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: %d", inm, w.Code)
+		}
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("If-None-Match", "\"other\"")
+	w = httptest.NewRecorder()
+	writeResponse(w, r, "text/html", body, "", time.Time{})
+	if w.Code != http.StatusOK {
+		t.Errorf("other etag: %d", w.Code)
+	}
+	r = httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	writeResponse(w, r, "text/html", body, "", time.Time{})
+	if w.Code != http.StatusOK {
+		t.Errorf("POST: %d", w.Code)
+	}
+}
+
+func TestWriteResponseModified(t *testing.T) {
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 600, time.UTC)
+	tests := map[string]int{
+		modified.Format(http.TimeFormat):                   http.StatusNotModified,
+		modified.Add(time.Hour).Format(http.TimeFormat):    http.StatusNotModified,
+		modified.Add(-time.Second).Format(http.TimeFormat): http.StatusOK,
+		"invalid": http.StatusOK,
+	}
+	for ims, code := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("If-Modified-Since", ims)
+		w := httptest.NewRecorder()
+		writeResponse(w, r, "text/css", []byte("p{}"), "", modified)
+		if w.Code != code || w.Header().Get("Last-Modified") != modified.Format(http.TimeFormat) {
+			t.Errorf("If-Modified-Since %s: %d %s", ims, w.Code, w.Header().Get("Last-Modified"))
+		}
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("If-Modified-Since", modified.Format(http.TimeFormat))
+	r.Header.Set("If-None-Match", "\"other\"")
+	w := httptest.NewRecorder()
+	writeResponse(w, r, "text/css", []byte("p{}"), "", modified)
+	if w.Code != http.StatusOK {
+		t.Errorf("If-Modified-Since with other etag: %d", w.Code)
+	}
+}
@@ -0,0 +1,244 @@
+package components
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jmu0/templates"
+	yaml "gopkg.in/yaml.v2"
+)
+
+//translations from locale files of components are merged in TemplateManager.LocalizationData (text -> locale -> translation),
+//keys from component locale files are prefixed with component name: <component>.<key>
+
+//translation marker in templates: {{t:key}}
+var translateMarker = regexp.MustCompile(`\{\{t:([\w.\-]+)\}\}`)
+
+var reportMissing bool
+var missingKeys = make(map[string]bool)
+var missingMutex sync.Mutex
+
+//addTranslations adds entries for locale to localization data with namespace prefix
+func addTranslations(data map[string]map[string]string, locale, namespace string, entries map[string]string) {
+	for key, text := range entries {
+		if namespace != "" {
+			key = namespace + "." + key
+		}
+		if _, ok := data[key]; !ok {
+			data[key] = make(map[string]string)
+		}
+		data[key][locale] = text
+	}
+}
+
+//lookupTranslation returns text for key in locale, falls back to default locale. empty texts are not translated
+func lookupTranslation(data map[string]map[string]string, locale, key string) (string, bool) {
+	if text := data[key][locale]; text != "" {
+		return text, true
+	}
+	if text := data[key][getDefaultLocale()]; text != "" {
+		return text, true
+	}
+	return "", false
+}
+
+//Translate returns translation of text for locale from LocalizationData (with component translations),
+//TemplateManager.Translate when not found. used for page title, description, meta and localized routes
+func Translate(tm *templates.TemplateManager, text, locale string) string {
+	if ret, ok := lookupTranslation(tm.LocalizationData, locale, text); ok {
+		return ret
+	}
+	return tm.Translate(text, locale)
+}
+
+//translateMarkers replaces {{t:key}} markers in html. key is looked up in namespace first,
+//unknown keys are replaced by the key itself and reported in debug mode
+func translateMarkers(tm *templates.TemplateManager, html, namespace, locale string) string {
+	if strings.Contains(html, "{{t:") == false {
+		return html
+	}
+	return translateMarker.ReplaceAllStringFunc(html, func(marker string) string {
+		key := translateMarker.FindStringSubmatch(marker)[1]
+		if namespace != "" {
+			if text, ok := lookupTranslation(tm.LocalizationData, locale, namespace+"."+key); ok {
+				return text
+			}
+		}
+		if text, ok := lookupTranslation(tm.LocalizationData, locale, key); ok {
+			return text
+		}
+		if namespace != "" {
+			key = namespace + "." + key
+		}
+		missing(locale, key)
+		return key
+	})
+}
+
+//fillTranslations sets missing or empty texts of locales to text of default locale
+func fillTranslations(data map[string]map[string]string, def string, locales []string) {
+	for _, texts := range data {
+		if texts[def] == "" {
+			continue
+		}
+		for _, locale := range locales {
+			if texts[locale] == "" {
+				texts[locale] = texts[def]
+			}
+		}
+	}
+}
+
+//missingTranslations returns keys (locale: key) that exist in one locale but are missing or empty in another
+func missingTranslations(data map[string]map[string]string, locales []string) []string {
+	var ret []string
+	for key, texts := range data {
+		for _, locale := range locales {
+			if texts[locale] == "" {
+				ret = append(ret, locale+": "+key)
+			}
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+//missing logs missing translation once per locale and key, only in debug mode
+func missing(locale, key string) {
+	missingMutex.Lock()
+	defer missingMutex.Unlock()
+	if reportMissing == false {
+		return
+	}
+	if missingKeys[locale+":"+key] == false {
+		missingKeys[locale+":"+key] = true
+		log.Println("Missing translation:", locale, key)
+	}
+}
+
+//setReportMissing enables missing translation logging, clears reported keys
+func setReportMissing(debug bool) {
+	missingMutex.Lock()
+	defer missingMutex.Unlock()
+	reportMissing = debug
+	missingKeys = make(map[string]bool)
+}
+
+//TranslationKeys returns keys of {{t:key}} markers in html, sorted and unique
+func TranslationKeys(html string) []string {
+	var ret []string
+	found := make(map[string]bool)
+	for _, match := range translateMarker.FindAllStringSubmatch(html, -1) {
+		if found[match[1]] == false {
+			found[match[1]] = true
+			ret = append(ret, match[1])
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+//LoadCatalogFiles loads <path>/locale/<locale>.yml and .json files, returns entries per locale
+func LoadCatalogFiles(path string) (map[string]map[string]string, error) {
+	ret := make(map[string]map[string]string)
+	for _, ext := range []string{".yml", ".yaml", ".json"} {
+		files, err := filepath.Glob(path + "/locale/*" + ext)
+		if err != nil {
+			return ret, err
+		}
+		for _, file := range files {
+			entries, err := ReadCatalogFile(file)
+			if err != nil {
+				return ret, err
+			}
+			locale := strings.TrimSuffix(filepath.Base(file), ext)
+			if _, ok := ret[locale]; !ok {
+				ret[locale] = make(map[string]string)
+			}
+			for key, text := range entries {
+				ret[locale][key] = text
+			}
+		}
+	}
+	return ret, nil
+}
+
+//ReadCatalogFile reads yml or json translation file. nested keys are joined with .
+func ReadCatalogFile(file string) (map[string]string, error) {
+	ret := make(map[string]string)
+	bytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return ret, err
+	}
+	var data interface{}
+	if filepath.Ext(file) == ".json" {
+		err = json.Unmarshal(bytes, &data)
+	} else {
+		err = yaml.Unmarshal(bytes, &data)
+	}
+	if err != nil {
+		return ret, fmt.Errorf("%s: %v", file, err)
+	}
+	flatten("", data, ret)
+	return ret, nil
+}
+
+//flatten adds values of nested maps to ret with keys joined by .
+func flatten(prefix string, value interface{}, ret map[string]string) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			flatten(join(key), val, ret)
+		}
+	case map[interface{}]interface{}:
+		for key, val := range v {
+			flatten(join(fmt.Sprint(key)), val, ret)
+		}
+	case nil:
+		if prefix != "" {
+			ret[prefix] = ""
+		}
+	default:
+		if prefix != "" {
+			ret[prefix] = fmt.Sprint(v)
+		}
+	}
+}
+
+//loadTranslations adds translations of component to app localization data
+func (a *App) loadTranslations(c Component) error {
+	entries, err := LoadCatalogFiles(c.Path)
+	if err != nil {
+		return err
+	}
+	for locale, e := range entries {
+		log.Println("Loading translations:", c.Name, locale)
+		addTranslations(a.TemplateManager.LocalizationData, locale, c.Name, e)
+	}
+	return nil
+}
+
+//copyLocalization returns copy of localization data, loaded translations are added to copy and not to data of base app
+func copyLocalization(data map[string]map[string]string) map[string]map[string]string {
+	ret := make(map[string]map[string]string, len(data))
+	for key, texts := range data {
+		ret[key] = make(map[string]string, len(texts))
+		for locale, text := range texts {
+			ret[key][locale] = text
+		}
+	}
+	return ret
+}
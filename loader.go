@@ -0,0 +1,89 @@
+package components
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jmu0/dbAPI/db"
+)
+
+//dataLoader loads component data for one request. calls run concurrently with a bounded
+//number of workers, identical calls (same component and path) are loaded once
+type dataLoader struct {
+	workers chan struct{}
+	calls   map[string]*dataCall
+	mutex   sync.Mutex
+}
+
+//dataCall data call in progress or done
+type dataCall struct {
+	done chan struct{}
+	data []map[string]interface{}
+	err  error
+}
+
+//newDataLoader creates loader for request
+func newDataLoader(workers int) *dataLoader {
+	if workers < 1 {
+		workers = 1
+	}
+	return &dataLoader{
+		workers: make(chan struct{}, workers),
+		calls:   make(map[string]*dataCall),
+	}
+}
+
+//load gets data for component, waits for identical call in progress. returns copy of rows
+func (l *dataLoader) load(ctx context.Context, cmp *Component, req *DataRequest, conn db.Conn) ([]map[string]interface{}, error) {
+	key := cmp.Name + "\x00" + req.Args["path"]
+	l.mutex.Lock()
+	call, loading := l.calls[key]
+	if !loading {
+		call = &dataCall{done: make(chan struct{})}
+		l.calls[key] = call
+	}
+	l.mutex.Unlock()
+	if loading {
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	} else {
+		l.run(ctx, call, cmp, req, conn)
+	}
+	return copyRows(call.data), call.err
+}
+
+//run runs data call when a worker is free
+func (l *dataLoader) run(ctx context.Context, call *dataCall, cmp *Component, req *DataRequest, conn db.Conn) {
+	defer close(call.done)
+	select {
+	case l.workers <- struct{}{}:
+	case <-ctx.Done():
+		call.err = ctx.Err()
+		return
+	}
+	defer func() {
+		<-l.workers
+		if rec := recover(); rec != nil {
+			call.err = recovered("panic loading data for "+cmp.Name, rec)
+		}
+	}()
+	call.data, call.err = cmp.GetDataCtx(ctx, req, conn)
+}
+
+//copyRows copies rows, rendering adds part data to rows
+func copyRows(data []map[string]interface{}) []map[string]interface{} {
+	if data == nil {
+		return nil
+	}
+	ret := make([]map[string]interface{}, len(data))
+	for i := range data {
+		ret[i] = make(map[string]interface{}, len(data[i]))
+		for k, v := range data[i] {
+			ret[i][k] = v
+		}
+	}
+	return ret
+}
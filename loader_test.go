@@ -0,0 +1,43 @@
+package components
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jmu0/dbAPI/db"
+)
+
+//TestDataLoaderDedup loads data of same component and path concurrently, data func must be called once
+func TestDataLoaderDedup(t *testing.T) {
+	var calls int32
+	cmp := Component{Name: "list"}
+	cmp.DataFuncCtx = func(ctx context.Context, req *DataRequest, conn db.Conn) ([]map[string]interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return []map[string]interface{}{{"id": "1"}}, nil
+	}
+	l := newDataLoader(4)
+	req := &DataRequest{Args: map[string]string{"path": "/list"}}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := l.load(context.Background(), &cmp, req, nil)
+			if err != nil || len(data) != 1 || data[0]["id"] != "1" {
+				t.Errorf("load: %v %v", data, err)
+				return
+			}
+			data[0]["part"] = "changed"
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("data func called %d times", calls)
+	}
+	other := &DataRequest{Args: map[string]string{"path": "/list/2"}}
+	if _, err := l.load(context.Background(), &cmp, other, nil); err != nil || calls != 2 {
+		t.Errorf("other path: %d %v", calls, err)
+	}
+}
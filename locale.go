@@ -0,0 +1,170 @@
+package components
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var locales = []string{"nl", "de", "en"}
+var defaultLocale = "nl"
+var localeMutex sync.RWMutex //locales are set again on reload
+
+type localeKey struct{}
+
+//SetLocales sets default locale and configured locales for requests not served by an App (App uses App.DefaultLocale and App.Locales)
+func SetLocales(def string, list []string) {
+	localeMutex.Lock()
+	defer localeMutex.Unlock()
+	if def != "" {
+		defaultLocale = def
+	}
+	if len(list) > 0 {
+		locales = list
+	}
+}
+
+//withLocale returns request with locale from path prefix or localized route
+func withLocale(r *http.Request, locale string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), localeKey{}, locale))
+}
+
+//getDefaultLocale returns default locale set with SetLocales
+func getDefaultLocale() string {
+	localeMutex.RLock()
+	defer localeMutex.RUnlock()
+	return defaultLocale
+}
+
+//requestLocales returns default locale and configured locales of the App serving the request,
+//locales set with SetLocales otherwise
+func requestLocales(r *http.Request) (string, []string) {
+	if s := requestSettings(r.Context()); s != nil && s.defaultLocale != "" {
+		return s.defaultLocale, s.locales
+	}
+	return defaultLocales()
+}
+
+//defaultLocales returns default locale and configured locales set with SetLocales
+func defaultLocales() (string, []string) {
+	localeMutex.RLock()
+	defer localeMutex.RUnlock()
+	return defaultLocale, locales
+}
+
+func isLocaleIn(locale string, list []string) bool {
+	for _, l := range list {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+//RequestLocale detects locale for request from: ?locale=, path prefix or
+//localized route, locale cookie, Accept-Language header, default locale
+func RequestLocale(r *http.Request) string {
+	locale, _ := detectLocale(r)
+	return locale
+}
+
+//detectLocale returns locale and where it was found
+func detectLocale(r *http.Request) (string, string) {
+	def, list := requestLocales(r)
+	if loc := r.URL.Query().Get("locale"); isLocaleIn(loc, list) {
+		return loc, "query"
+	}
+	if loc, ok := r.Context().Value(localeKey{}).(string); ok && isLocaleIn(loc, list) {
+		return loc, "path"
+	}
+	if cookie, err := r.Cookie("locale"); err == nil && isLocaleIn(cookie.Value, list) {
+		return cookie.Value, "cookie"
+	}
+	if loc := acceptLanguage(r.Header.Get("Accept-Language"), list); loc != "" {
+		return loc, "header"
+	}
+	return def, "default"
+}
+
+//rememberLocale sets locale cookie when locale is chosen with ?locale= or path
+func rememberLocale(w http.ResponseWriter, r *http.Request) {
+	locale, source := detectLocale(r)
+	if source == "query" || source == "path" {
+		http.SetCookie(w, &http.Cookie{
+			Name:   "locale",
+			Value:  locale,
+			Path:   "/",
+			MaxAge: 365 * 24 * 3600,
+		})
+	}
+}
+
+//acceptLanguage returns first locale of list from Accept-Language header
+func acceptLanguage(header string, list []string) string {
+	type lang struct {
+		tag string
+		q   float64
+	}
+	var langs []lang
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		langs = append(langs, lang{tag: tag, q: q})
+	}
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].q > langs[j].q
+	})
+	for _, l := range langs {
+		if isLocaleIn(l.tag, list) {
+			return l.tag
+		}
+		if base := strings.Split(l.tag, "-")[0]; isLocaleIn(base, list) {
+			return base
+		}
+	}
+	return ""
+}
+
+//localizeRoute translates static segments of route to locale
+func (a *App) localizeRoute(route, locale string) string {
+	segments := strings.Split(strings.Trim(route, "/"), "/")
+	for i, seg := range segments {
+		if seg != "" && !strings.HasPrefix(seg, "{") {
+			segments[i] = strings.Replace(Translate(&a.TemplateManager, seg, locale), " ", "", -1)
+		}
+	}
+	ret := "/" + strings.Join(segments, "/")
+	if strings.HasSuffix(route, "/") && ret != "/" {
+		ret += "/"
+	}
+	return ret
+}
+
+//handleLocalePrefix serves /<locale>/... with prefix removed and locale set
+func (a *App) handleLocalePrefix(locale string) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r2 := withLocale(r, locale)
+		u := *r.URL
+		r2.URL = &u
+		r2.URL.Path = strings.TrimPrefix(r.URL.Path, "/"+locale)
+		if r2.URL.RawPath != "" {
+			r2.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, "/"+locale)
+		}
+		a.Router.ServeHTTP(w, r2)
+	}
+}
@@ -0,0 +1,60 @@
+package components
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAcceptLanguage(t *testing.T) {
+	list := []string{"nl", "de", "en"}
+	tests := map[string]string{
+		"en-US,en;q=0.9,nl;q=0.8": "en",
+		"fr;q=0.9,de;q=0.5":       "de",
+		"nl;q=0.2,de;q=0.8":       "de",
+		"de-AT":                   "de",
+		"fr, es":                  "",
+		"":                        "",
+	}
+	for header, want := range tests {
+		if got := acceptLanguage(header, list); got != want {
+			t.Errorf("%q: got %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestDetectLocale(t *testing.T) {
+	s := &appSettings{defaultLocale: "en", locales: []string{"en", "fr"}}
+	request := func(url, header string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		r.Header.Set("Accept-Language", header)
+		return r.WithContext(context.WithValue(r.Context(), settingsKey{}, s))
+	}
+	tests := []struct {
+		r      *http.Request
+		locale string
+		source string
+	}{
+		{request("/?locale=fr", "en"), "fr", "query"},
+		{request("/?locale=nl", "fr"), "fr", "header"},
+		{withLocale(request("/", ""), "fr"), "fr", "path"},
+		{request("/", "nl, de"), "en", "default"},
+	}
+	for i, test := range tests {
+		if locale, source := detectLocale(test.r); locale != test.locale || source != test.source {
+			t.Errorf("%d: got %s %s, want %s %s", i, locale, source, test.locale, test.source)
+		}
+	}
+}
+
+func TestFillTranslations(t *testing.T) {
+	data := map[string]map[string]string{"title": {"nl": "Titel", "en": ""}, "other": {"en": "Other"}}
+	fillTranslations(data, "nl", []string{"nl", "de", "en"})
+	if data["title"]["en"] != "Titel" || data["title"]["de"] != "Titel" {
+		t.Errorf("title: %v", data["title"])
+	}
+	if len(data["other"]) != 1 {
+		t.Errorf("key without default locale filled: %v", data["other"])
+	}
+}
@@ -0,0 +1,188 @@
+package components
+
+import (
+	"encoding/json"
+	"html"
+	"log"
+	"net/http"
+	"strings"
+
+	"git.muysers.nl/jmu0/jwt"
+)
+
+//credentials for login request, from json body or form
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	URI      string `json:"uri"`
+}
+
+//getCredentials gets username, password and uri from json body or form
+func getCredentials(r *http.Request) (credentials, error) {
+	var c credentials
+	if strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+		err := json.NewDecoder(r.Body).Decode(&c)
+		if err != nil {
+			return c, err
+		}
+	} else {
+		err := r.ParseForm()
+		if err != nil {
+			return c, err
+		}
+		c.Username = r.FormValue("username")
+		c.Password = r.FormValue("password")
+		c.URI = r.FormValue("uri")
+	}
+	if c.URI == "" {
+		c.URI = r.URL.Query().Get("uri")
+	}
+	c.URI = localURI(c.URI)
+	return c, nil
+}
+
+//localURI returns uri if it is a path on this server, to prevent open redirects
+func localURI(uri string) string {
+	if strings.HasPrefix(uri, "/") && !strings.HasPrefix(uri, "//") && !strings.HasPrefix(uri, "/\\") {
+		return uri
+	}
+	return ""
+}
+
+//renderLogin renders login component, returns false if it doesn't exist.
+//uri must be local, values are escaped (templates insert them as html)
+func (a *App) renderLogin(w http.ResponseWriter, r *http.Request, status int, errMsg, uri string) bool {
+	login, ok := a.Components[a.Login]
+	if !ok {
+		return false
+	}
+	data := make(map[string]interface{})
+	data["error"] = html.EscapeString(errMsg)
+	data["uri"] = html.EscapeString(localURI(uri))
+	log.Println("Rendering login")
+	content, err := login.Render("", GetRequestArgs(r), data)
+	if err != nil {
+		log.Println("ERROR:", err)
+		return false
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write([]byte(content))
+	return true
+}
+
+//loggedIn writes response after login or refresh: redirect to uri or json with name (and token).
+//token that is not in a cookie would be lost with redirect, json with name, token and uri is returned instead
+func loggedIn(w http.ResponseWriter, r *http.Request, p *Principal, uri string) {
+	token := strings.TrimPrefix(w.Header().Get("Authorization"), "Bearer ")
+	if uri != "" && (token == "" || w.Header().Get("Set-Cookie") != "") {
+		http.Redirect(w, r, uri, http.StatusSeeOther)
+		return
+	}
+	res := map[string]string{"name": p.Name}
+	if token != "" {
+		res["token"] = token
+	}
+	if uri != "" {
+		res["uri"] = uri
+	}
+	bytes, err := json.Marshal(res)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(bytes)
+}
+
+//handleLogin handles /auth/login. checks credentials with App.AuthFunc
+func (a *App) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if j, ok := a.Authenticator.(*JWTAuthenticator); ok && len(j.Secret) == 0 {
+		//tokens issued by jwt package
+		err := jwt.HandleAuth(w, r, a.AuthFunc)
+		if err != nil {
+			log.Println("Auth failed:", err)
+		}
+		return
+	}
+	lh, ok := a.Authenticator.(LoginHandler)
+	if !ok {
+		http.Error(w, ErrLoginNotSupported.Error(), http.StatusNotImplemented)
+		return
+	}
+	c, err := getCredentials(r)
+	if err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	claims, err := a.AuthFunc(c.Username, c.Password)
+	if err != nil {
+		log.Println("Auth failed:", err)
+		if c.URI == "" || a.renderLogin(w, r, http.StatusUnauthorized, err.Error(), c.URI) == false {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		}
+		return
+	}
+	if claims == nil {
+		claims = make(map[string]string)
+	}
+	if _, ok := claims["name"]; !ok {
+		claims["name"] = c.Username
+	}
+	p := &Principal{Name: claims["name"], Claims: claims}
+	err = lh.Login(w, r, p)
+	if err != nil {
+		log.Println("ERROR login:", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Println("Authenticated:", p.Name)
+	loggedIn(w, r, p, c.URI)
+}
+
+//handleLogout handles /auth/logout (POST, prevents logout by links on other sites). redirects to uri when given
+func (a *App) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if lh, ok := a.Authenticator.(LoginHandler); ok {
+		lh.Logout(w, r)
+	}
+	if uri := localURI(r.URL.Query().Get("uri")); uri != "" {
+		http.Redirect(w, r, uri, http.StatusSeeOther)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+//handleRefresh handles /auth/refresh. extends session or issues new token
+func (a *App) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	lh, ok := a.Authenticator.(LoginHandler)
+	if !ok {
+		http.Error(w, ErrLoginNotSupported.Error(), http.StatusNotImplemented)
+		return
+	}
+	p, err := Authenticate(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	err = lh.Refresh(w, r)
+	if err == ErrLoginNotSupported {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	} else if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	loggedIn(w, r, p, localURI(r.URL.Query().Get("uri")))
+}
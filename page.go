@@ -1,24 +1,38 @@
 package components
 
-import "github.com/jmu0/dbAPI/db"
+import (
+	"strings"
+
+	"github.com/jmu0/dbAPI/db"
+)
 
 //Page struct for page data
 type Page struct {
-	Route      string `json:"route" yaml:"route"`
-	Auth       bool   `json:"auth" yaml:"auth"`
-	Components []Part `json:"components" yaml:"components"`
+	Route      string   `json:"route" yaml:"route"`
+	Auth       bool     `json:"auth" yaml:"auth"`
+	Roles      []string `json:"roles" yaml:"roles"`   //user needs one of roles
+	Claims     []string `json:"claims" yaml:"claims"` //conditions on jwt claims
+	Components []Part   `json:"components" yaml:"components"`
+
+	Title       string            `json:"title" yaml:"title"`             //translated, default: app title
+	Description string            `json:"description" yaml:"description"` //translated
+	Meta        map[string]string `json:"meta" yaml:"meta"`               //meta tags: name: content
+	Canonical   string            `json:"canonical" yaml:"canonical"`
+	Layout      string            `json:"layout" yaml:"layout"`   //html file in components path, default: main
+	Timeout     string            `json:"timeout" yaml:"timeout"` //deadline for data of components, ex: 5s
 }
 
 //Render renders the components
 // func (p *Page) Render(path, locale string, components map[string]Component, conn db.Conn) (string, error) {
 func (p *Page) Render(args map[string]string, components map[string]Component, conn db.Conn) (string, error) {
-	var html string
-	for _, comp := range p.Components {
-		cmphtml, err := comp.Render(args, components, conn)
-		if err != nil {
-			return "", err
-		}
-		html += cmphtml
+	return p.RenderWith(argsContext(args), components, conn)
+}
+
+//RenderWith renders the components with request scoped render context
+func (p *Page) RenderWith(rc *RenderContext, components map[string]Component, conn db.Conn) (string, error) {
+	html, err := renderParts(rc, p.Components, components, conn)
+	if err != nil {
+		return "", err
 	}
-	return html, nil
+	return strings.Join(html, ""), nil
 }
@@ -0,0 +1,228 @@
+package components
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Param struct for query route bind parameter
+type Param struct {
+	Name     string `yaml:"name"`
+	Type     string `yaml:"type"`     //string (default), int, float, bool, date, datetime
+	Source   string `yaml:"source"`   //path (default), query, claim, body
+	Key      string `yaml:"key"`      //name of query arg, claim or body field. defaults to name
+	Required bool   `yaml:"required"` //400 when missing and no default
+	Default  string `yaml:"default"`  //used when value is missing
+}
+
+//ParamError error for invalid or missing parameter values
+type ParamError struct {
+	Param string
+	Msg   string
+}
+
+func (e *ParamError) Error() string {
+	return "Invalid parameter " + e.Param + ": " + e.Msg
+}
+
+//compileSQL replaces named (:name) or positional (?) parameters with placeholders (?),
+//queryArgs and executeArgs convert them for the driver. returns query and parameter names in order of appearance.
+//positional parameters have an empty name. legacy fmt placeholders (%s, %d, %v) outside quotes,
+//or quoted as the whole literal ('%s'), are positional parameters. queries with legacy placeholders
+//keep the fmt escape: %% is replaced with %
+func compileSQL(sql string) (string, []string, error) {
+	var out strings.Builder
+	var names = make([]string, 0)
+	var quote rune
+	var named, positional, legacy bool
+	rs := []rune(sql)
+	for i := 0; i < len(rs); i++ {
+		c := rs[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			out.WriteRune(c)
+			continue
+		}
+		switch {
+		case (c == '\'' || c == '"') && i+3 < len(rs) && isLegacyPlaceholder(rs[i+1:]) && rs[i+3] == c:
+			positional, legacy = true, true
+			names = append(names, "")
+			out.WriteRune('?')
+			i += 3
+		case c == '%' && i+1 < len(rs) && rs[i+1] == '%':
+			//escaped %, not a placeholder
+			out.WriteString("%%")
+			i++
+		case c == '%' && isLegacyPlaceholder(rs[i:]):
+			positional, legacy = true, true
+			names = append(names, "")
+			out.WriteRune('?')
+			i++
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			out.WriteRune(c)
+		case c == '?':
+			positional = true
+			names = append(names, "")
+			out.WriteRune(c)
+		case c == ':' && i+1 < len(rs) && isIdentStart(rs[i+1]) && (i == 0 || rs[i-1] != ':'):
+			j := i + 1
+			for j < len(rs) && isIdentChar(rs[j]) {
+				j++
+			}
+			named = true
+			names = append(names, string(rs[i+1:j]))
+			out.WriteRune('?')
+			i = j - 1
+		default:
+			out.WriteRune(c)
+		}
+	}
+	if named && positional {
+		return "", nil, errors.New("Can't mix named and positional parameters in: " + sql)
+	}
+	if legacy {
+		return strings.Replace(out.String(), "%%", "%", -1), names, nil
+	}
+	return out.String(), names, nil
+}
+
+//isLegacyPlaceholder checks if rs starts with %s, %d or %v
+func isLegacyPlaceholder(rs []rune) bool {
+	return len(rs) > 1 && rs[0] == '%' && (rs[1] == 's' || rs[1] == 'd' || rs[1] == 'v')
+}
+
+func isIdentStart(c rune) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c rune) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+//Coerce converts string value to parameter type
+func (p *Param) Coerce(value string) (interface{}, error) {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(p.Type) {
+	case "", "string":
+		return value, nil
+	case "int":
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, &ParamError{Param: p.Name, Msg: "not an int"}
+		}
+		return v, nil
+	case "float":
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, &ParamError{Param: p.Name, Msg: "not a float"}
+		}
+		return v, nil
+	case "bool":
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, &ParamError{Param: p.Name, Msg: "not a bool"}
+		}
+		return v, nil
+	case "date":
+		v, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return nil, &ParamError{Param: p.Name, Msg: "not a date (yyyy-mm-dd)"}
+		}
+		return v, nil
+	case "datetime":
+		for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05"} {
+			if v, err := time.Parse(layout, value); err == nil {
+				return v, nil
+			}
+		}
+		return nil, &ParamError{Param: p.Name, Msg: "not a datetime"}
+	default:
+		return nil, errors.New("Unknown parameter type: " + p.Type)
+	}
+}
+
+//check checks type, source and default of parameter
+func (p *Param) check() error {
+	switch strings.ToLower(p.Source) {
+	case "", "path", "query", "claim", "body":
+	default:
+		return errors.New("Unknown parameter source: " + p.Source)
+	}
+	switch strings.ToLower(p.Type) {
+	case "", "string", "int", "float", "bool", "date", "datetime":
+	default:
+		return errors.New("Unknown parameter type: " + p.Type)
+	}
+	if p.Default != "" {
+		if _, err := p.Coerce(p.Default); err != nil {
+			return errors.New("Invalid default of parameter " + p.Name + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+//key returns name of value in source
+func (p *Param) key() string {
+	if p.Key != "" {
+		return p.Key
+	}
+	return p.Name
+}
+
+//requestValues gets parameter values from request for declared params with
+//source query, claim or body. path params are added by caller.
+func requestValues(params []Param, r *http.Request) (map[string]string, error) {
+	var values = make(map[string]string)
+	var body map[string]interface{}
+	var claims map[string]string
+	for _, p := range params {
+		switch strings.ToLower(p.Source) {
+		case "", "path":
+			continue
+		case "query":
+			if v, ok := r.URL.Query()[p.key()]; ok {
+				values[p.Name] = strings.Join(v, ",")
+			}
+		case "claim":
+			if claims == nil {
+				claims = GetClaims(r)
+			}
+			if v, ok := claims[p.key()]; ok {
+				values[p.Name] = v
+			}
+		case "body":
+			if body == nil {
+				body = make(map[string]interface{})
+				if r.Body != nil && r.Method != http.MethodGet {
+					err := json.NewDecoder(r.Body).Decode(&body)
+					if err != nil && err != io.EOF {
+						return nil, &ParamError{Param: "body", Msg: "invalid json"}
+					}
+				}
+			}
+			if v, ok := body[p.key()]; ok && v != nil {
+				switch val := v.(type) {
+				case string:
+					values[p.Name] = val
+				case float64:
+					values[p.Name] = strconv.FormatFloat(val, 'f', -1, 64)
+				case bool:
+					values[p.Name] = strconv.FormatBool(val)
+				default:
+					return nil, &ParamError{Param: p.Name, Msg: "not a scalar value"}
+				}
+			}
+		default:
+			return nil, errors.New("Unknown parameter source: " + p.Source)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,96 @@
+package components
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompileSQL(t *testing.T) {
+	tests := []struct {
+		sql   string
+		query string
+		names []string
+	}{
+		{"select * from t where a=:a and b = :b_2", "select * from t where a=? and b = ?", []string{"a", "b_2"}},
+		{"select * from t where a=? and b=?", "select * from t where a=? and b=?", []string{"", ""}},
+		{"select * from t where a=:a or b=:a", "select * from t where a=? or b=?", []string{"a", "a"}},
+		{"select ':a', \"?\" from t where a=:a", "select ':a', \"?\" from t where a=?", []string{"a"}},
+		{"select a::text from t where b=:b", "select a::text from t where b=?", []string{"b"}},
+		{"select * from t where a='%s' and b=%d", "select * from t where a=? and b=?", []string{"", ""}},
+		{"select * from t where a=\"%v\"", "select * from t where a=?", []string{""}},
+		{"select date_format(d, '%d-%m-%Y') from t where a=%s", "select date_format(d, '%d-%m-%Y') from t where a=?", []string{""}},
+		{"select * from t where a like '%dahlia%'", "select * from t where a like '%dahlia%'", []string{}},
+		{"select `a:b` from t", "select `a:b` from t", []string{}},
+		{"select * from t where a like '%%dahlia%%' and b=%s", "select * from t where a like '%dahlia%' and b=?", []string{""}},
+		{"select a %% 2 from t where b=%%s and c=%d", "select a % 2 from t where b=%s and c=?", []string{""}},
+		{"select * from t where a like '%%x' and b=?", "select * from t where a like '%%x' and b=?", []string{""}},
+	}
+	for _, test := range tests {
+		query, names, err := compileSQL(test.sql)
+		if err != nil {
+			t.Errorf("%s: %v", test.sql, err)
+			continue
+		}
+		if query != test.query || !reflect.DeepEqual(names, test.names) {
+			t.Errorf("%s:\n got %q %q\nwant %q %q", test.sql, query, names, test.query, test.names)
+		}
+	}
+}
+
+func TestCompileSQLMixed(t *testing.T) {
+	if _, _, err := compileSQL("select * from t where a=:a and b=?"); err == nil {
+		t.Error("expected error for named and positional parameters")
+	}
+}
+
+func TestNumberPlaceholders(t *testing.T) {
+	query := numberPlaceholders("select '?', a from t where a = ? and b in (?, ?) and c = \"?\"")
+	if query != "select '?', a from t where a = $1 and b in ($2, $3) and c = \"?\"" {
+		t.Errorf("got %s", query)
+	}
+	if query := rebind("select * from t where a = ?", nil); query != "select * from t where a = ?" {
+		t.Errorf("driver without numbered placeholders: %s", query)
+	}
+}
+
+func TestParamCheck(t *testing.T) {
+	for _, p := range []Param{{Name: "a"}, {Name: "a", Type: "int", Source: "query", Default: "1"}, {Name: "a", Type: "date", Source: "claim"}} {
+		if err := p.check(); err != nil {
+			t.Errorf("%v: %v", p, err)
+		}
+	}
+	for _, p := range []Param{{Name: "a", Type: "integer"}, {Name: "a", Source: "header"}, {Name: "a", Type: "int", Default: "x"}} {
+		if err := p.check(); err == nil {
+			t.Errorf("%v: expected error", p)
+		}
+	}
+}
+
+func TestRequestValues(t *testing.T) {
+	params := []Param{
+		{Name: "id"},
+		{Name: "q", Source: "query", Key: "search"},
+		{Name: "klant", Source: "claim"},
+		{Name: "n", Source: "body"},
+		{Name: "ok", Source: "body"},
+	}
+	r := httptest.NewRequest(http.MethodPost, "/api/items/1?search=a&search=b", strings.NewReader(`{"n":1.5,"ok":true}`))
+	r = WithPrincipal(r, &Principal{Name: "user", Claims: map[string]string{"klant": "k1"}})
+	values, err := requestValues(params, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"q": "a,b", "klant": "k1", "n": "1.5", "ok": "true"}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("got %v, want %v", values, want)
+	}
+	r = httptest.NewRequest(http.MethodPost, "/api/items", strings.NewReader(`{"n":{"a":1}}`))
+	if _, err := requestValues(params, r); err == nil {
+		t.Error("expected error for object in body")
+	} else if _, ok := err.(*ParamError); !ok {
+		t.Errorf("expected ParamError: %v", err)
+	}
+}
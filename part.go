@@ -4,37 +4,77 @@ import (
 	"errors"
 	"log"
 	"strings"
+	"sync"
 
 	"github.com/jmu0/dbAPI/db"
 )
 
 //Part stores component names for app struct
 type Part struct {
-	Name       string `json:"name" yaml:"name"`
-	Template   string `json:"template" yaml:"template"`
-	Components []Part `json:"components" yaml:"components"`
+	Name       string   `json:"name" yaml:"name"`
+	Template   string   `json:"template" yaml:"template"`
+	Roles      []string `json:"roles" yaml:"roles"`   //render part only for users with one of roles
+	Claims     []string `json:"claims" yaml:"claims"` //render part only when conditions on jwt claims are true
+	Components []Part   `json:"components" yaml:"components"`
 }
 
 //Render renders part (recursive)
 func (p *Part) Render(args map[string]string, components map[string]Component, conn db.Conn) (string, error) {
-	var err error
-	var html, itemhtml, cmpName string
+	return p.RenderWith(argsContext(args), components, conn)
+}
+
+//RenderWith renders part with request scoped render context (recursive)
+func (p *Part) RenderWith(rc *RenderContext, components map[string]Component, conn db.Conn) (string, error) {
+	var html, itemhtml, cmpName, tmplName string
 	var data []map[string]interface{}
+	args := rc.Args
+	if len(p.Roles) > 0 || len(p.Claims) > 0 {
+		ok, err := Permitted(p.Roles, p.Claims, rc.Claims())
+		if err != nil {
+			log.Println("(part.Render):", err)
+		}
+		if ok == false {
+			return "", nil
+		}
+	}
 	if cmp, ok := components[p.Name]; ok {
+		if cmp.dataPermitted(rc.Principal) == false {
+			//api route used as data source requires auth, roles or claims
+			return "", nil
+		}
+		//load data while rendering child parts
+		var dataErr error
+		loaded := make(chan struct{})
+		if rc.loader != nil {
+			go func() {
+				defer close(loaded)
+				data, dataErr = rc.loadData(&cmp, conn)
+			}()
+		} else {
+			data, dataErr = rc.loadData(&cmp, conn)
+			close(loaded)
+		}
+		children, err := renderParts(rc, p.Components, components, conn)
+		<-loaded
+		if err != nil {
+			return "", err
+		}
+		if dataErr != nil && rc.Context().Err() != nil {
+			//deadline of page or request cancelled, don't render page without data
+			return "", rc.Context().Err()
+		}
 		var partData = make(map[string]interface{})
-		for _, prt := range p.Components {
-			partData[prt.Name], err = prt.Render(args, components, conn)
-			if err != nil {
-				return "", err
-			}
+		for i, prt := range p.Components {
+			partData[prt.Name] = children[i]
 		}
-		if p.Template == "" {
+		err = dataErr
+		tmplName = p.Template
+		if tmplName == "" {
 			for tmpl := range cmp.TemplateManager.GetTemplates() {
-				p.Template = tmpl
+				tmplName = tmpl
 				break
 			}
 		}
-		data, err = cmp.GetData(args, conn)
 		if err != nil || len(data) == 0 {
 			data = make([]map[string]interface{}, 0)
 			data = append(data, partData)
@@ -48,15 +88,22 @@ func (p *Part) Render(args map[string]string, components map[string]Component, c
 				}
 			}
 		}
+		for i := range data {
+			for k, v := range rc.Params {
+				if _, ok := data[i][k]; !ok {
+					data[i][k] = v
+				}
+			}
+		}
 		if len(data) <= 1 {
 			d := make(map[string]interface{})
 			if len(data) == 1 {
 				d = data[0]
 			}
-			html, err = cmp.Render(p.Template, args, d)
+			html, err = cmp.Render(tmplName, args, d)
 		} else if len(data) > 1 {
 			for i := range data {
-				itemhtml, err = cmp.Render(p.Template, args, data[i])
+				itemhtml, err = cmp.Render(tmplName, args, data[i])
 				if err != nil {
 					return "", err
 				}
@@ -69,3 +116,39 @@ func (p *Part) Render(args map[string]string, components map[string]Component, c
 	}
 	return "", errors.New("Component not found for part: " + p.Name)
 }
+
+//renderParts renders parts, concurrently when request has a data loader. html is returned in order of parts
+func renderParts(rc *RenderContext, parts []Part, components map[string]Component, conn db.Conn) ([]string, error) {
+	html := make([]string, len(parts))
+	if rc.loader == nil || len(parts) < 2 {
+		for i := range parts {
+			h, err := parts[i].RenderWith(rc, components, conn)
+			if err != nil {
+				return nil, err
+			}
+			html[i] = h
+		}
+		return html, nil
+	}
+	errs := make([]error, len(parts))
+	var wg sync.WaitGroup
+	for i := range parts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() {
+				if rec := recover(); rec != nil {
+					errs[i] = recovered("panic rendering part "+parts[i].Name, rec)
+				}
+			}()
+			html[i], errs[i] = parts[i].RenderWith(rc, components, conn)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return html, nil
+}
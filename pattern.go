@@ -0,0 +1,81 @@
+package components
+
+import "strings"
+
+//routePattern page route with path parameters, ex: /plant/{naamcode}/maat/{maat:int}
+type routePattern struct {
+	segments []string
+	page     Page
+	locale   string //locale for localized route
+}
+
+//hasParams checks if route has path parameters
+func hasParams(route string) bool {
+	return strings.Contains(route, "{")
+}
+
+//parsePattern parses page route
+func parsePattern(page Page) routePattern {
+	return routePattern{
+		segments: strings.Split(strings.Trim(page.Route, "/"), "/"),
+		page:     page,
+	}
+}
+
+//prefix returns static part of route before first parameter, for registering on ServeMux
+func (p routePattern) prefix() string {
+	var ret = "/"
+	for _, seg := range p.segments {
+		if strings.HasPrefix(seg, "{") {
+			break
+		}
+		ret += seg + "/"
+	}
+	return ret
+}
+
+//match matches path with pattern, returns path parameters and typed path parameters.
+//path doesn't match when parameter has wrong type ({name:type}, types like api.yml params)
+func (p routePattern) match(path string) (map[string]string, map[string]interface{}, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) != len(p.segments) {
+		return nil, nil, false
+	}
+	params := make(map[string]string)
+	typed := make(map[string]interface{})
+	for i, seg := range p.segments {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			if segments[i] == "" {
+				return nil, nil, false
+			}
+			param := Param{Name: seg[1 : len(seg)-1]}
+			if i := strings.Index(param.Name, ":"); i > -1 {
+				param.Type = param.Name[i+1:]
+				param.Name = param.Name[:i]
+			}
+			value, err := param.Coerce(segments[i])
+			if err != nil {
+				return nil, nil, false
+			}
+			params[param.Name] = segments[i]
+			typed[param.Name] = value
+		} else if seg != segments[i] {
+			return nil, nil, false
+		}
+	}
+	return params, typed, true
+}
+
+//matchPage checks if path matches page without path parameters: the route itself or
+//the route with one key segment (legacy keys, ex: /plant/abc:12). root page only matches itself
+func (pr *pageRoute) matchPage(path string) bool {
+	route := pr.page.Route
+	if path == route || path+"/" == route {
+		return true
+	}
+	if pr.exact || !strings.HasPrefix(path, route) {
+		return false
+	}
+	key := strings.TrimSuffix(path[len(route):], "/")
+	return key != "" && !strings.Contains(key, "/")
+}
@@ -0,0 +1,43 @@
+package components
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPatternMatch(t *testing.T) {
+	pattern := parsePattern(Page{Route: "/plant/{naamcode}/maat/{maat:int}"})
+	if prefix := pattern.prefix(); prefix != "/plant/" {
+		t.Errorf("prefix: %s", prefix)
+	}
+	params, typed, ok := pattern.match("/plant/abc/maat/12/")
+	if !ok || !reflect.DeepEqual(params, map[string]string{"naamcode": "abc", "maat": "12"}) || typed["maat"] != int64(12) {
+		t.Errorf("match: %v %v %v", params, typed, ok)
+	}
+	for _, path := range []string{"/plant/abc/maat/x", "/plant/abc/maat", "/plant/abc/maat/12/extra", "/plant//maat/12", "/other/abc/maat/12"} {
+		if _, _, ok := pattern.match(path); ok {
+			t.Errorf("%s: expected no match", path)
+		}
+	}
+}
+
+func TestMatchPage(t *testing.T) {
+	pr := &pageRoute{page: &Page{Route: "/plant/"}}
+	for path, want := range map[string]bool{
+		"/plant/":        true,
+		"/plant":         true,
+		"/plant/abc:12":  true,
+		"/plant/abc:12/": true,
+		"/plant/abc/def": false,
+		"/plant/a/b/c/":  false,
+		"/planten/":      false,
+	} {
+		if got := pr.matchPage(path); got != want {
+			t.Errorf("%s: got %v, want %v", path, got, want)
+		}
+	}
+	root := &pageRoute{page: &Page{Route: "/"}, exact: true}
+	if root.matchPage("/") == false || root.matchPage("/abc") == true {
+		t.Error("root page matches other paths")
+	}
+}
@@ -0,0 +1,130 @@
+package components
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jmu0/dbAPI/db"
+)
+
+//sqlConn is implemented by connections that expose the underlying *sql.DB
+type sqlConn interface {
+	GetConnection() *sql.DB
+}
+
+//queryArgs runs select query with bind arguments. query is cancelled with ctx when run with bind arguments
+func queryArgs(ctx context.Context, conn db.Conn, query string, args []interface{}) ([]map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return conn.Query(query)
+	}
+	c, ok := conn.(sqlConn)
+	if !ok {
+		return nil, errors.New("Connection does not support query parameters")
+	}
+	sqlDB := c.GetConnection()
+	rows, err := sqlDB.QueryContext(ctx, rebind(query, sqlDB.Driver()), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var ret = make([]map[string]interface{}, 0)
+	for rows.Next() {
+		values := make([]interface{}, len(cols))
+		pointers := make([]interface{}, len(cols))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		err = rows.Scan(pointers...)
+		if err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{})
+		for i, col := range cols {
+			if b, ok := values[i].([]byte); ok {
+				row[col] = string(b)
+			} else {
+				row[col] = values[i]
+			}
+		}
+		ret = append(ret, row)
+	}
+	return ret, rows.Err()
+}
+
+//executeArgs runs insert/update/delete query with bind arguments. returns last insert id and affected rows
+func executeArgs(ctx context.Context, conn db.Conn, query string, args []interface{}) (int64, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, 0, err
+	}
+	if len(args) == 0 {
+		return conn.Execute(query)
+	}
+	c, ok := conn.(sqlConn)
+	if !ok {
+		return 0, 0, errors.New("Connection does not support query parameters")
+	}
+	sqlDB := c.GetConnection()
+	res, err := sqlDB.ExecContext(ctx, rebind(query, sqlDB.Driver()), args...)
+	if err != nil {
+		return 0, 0, err
+	}
+	id, _ := res.LastInsertId()
+	n, err := res.RowsAffected()
+	return id, n, err
+}
+
+//numberedPlaceholders checks if driver uses $1, $2.. placeholders (postgres)
+func numberedPlaceholders(d driver.Driver) bool {
+	t := reflect.TypeOf(d)
+	if t == nil {
+		return false
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	pkg := t.PkgPath()
+	return strings.Contains(pkg, "lib/pq") || strings.Contains(pkg, "pgx") || strings.Contains(pkg, "postgres")
+}
+
+//rebind replaces ? placeholders outside quotes with $1, $2.. for drivers with numbered placeholders
+func rebind(query string, d driver.Driver) string {
+	if !numberedPlaceholders(d) {
+		return query
+	}
+	return numberPlaceholders(query)
+}
+
+//numberPlaceholders replaces ? placeholders outside quotes with $1, $2..
+func numberPlaceholders(query string) string {
+	var out strings.Builder
+	var quote rune
+	n := 0
+	for _, c := range query {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?':
+			n++
+			out.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		out.WriteRune(c)
+	}
+	return out.String()
+}
@@ -0,0 +1,149 @@
+package components
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/radovskyb/watcher"
+)
+
+var reloadMutex sync.Mutex    //one (re)load at a time
+var handlerMutex sync.RWMutex //guards swapping App.Router
+
+//appSettings settings of a load of the App. requests served by the App resolve their authenticator, cache and locales with it
+type appSettings struct {
+	authenticator Authenticator
+	cache         Cache
+	defaultLocale string
+	locales       []string
+}
+
+type settingsKey struct{}
+
+//requestSettings returns settings of the App serving the request, nil when not served by an App
+func requestSettings(ctx context.Context) *appSettings {
+	s, _ := ctx.Value(settingsKey{}).(*appSettings)
+	return s
+}
+
+//ErrNotInitialized app is reloaded before Init
+var ErrNotInitialized = errors.New("App not initialized")
+
+//ServeHTTP serves request with routes and settings of current load. requests in flight finish with the load they started with
+func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handlerMutex.RLock()
+	h := a.Router
+	s := a.settings
+	handlerMutex.RUnlock()
+	if h == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if s != nil {
+		r = r.WithContext(context.WithValue(r.Context(), settingsKey{}, s))
+	}
+	h.ServeHTTP(w, r)
+}
+
+//Reload reloads config, components, api routes, translations and templates without restarting the server.
+//routes are swapped when loading succeeds, on error the current routes keep serving
+func (a *App) Reload() error {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+	if a.base == nil {
+		return ErrNotInitialized
+	}
+	log.Println("Reloading app...")
+	next, err := a.load()
+	if err != nil {
+		log.Println("ERROR reloading app:", err)
+		return err
+	}
+	a.swap(next)
+	log.Println("Reloaded app")
+	return nil
+}
+
+//swap replaces app with newly loaded app, keeps Mux of caller
+func (a *App) swap(next *App) {
+	handlerMutex.Lock()
+	defer handlerMutex.Unlock()
+	mux := a.Mux
+	*a = *next
+	a.Mux = mux
+	setReportMissing(a.Debug)
+}
+
+//handleLoaded serves time of load that serves requests, build run reloads browser when it changes (debug mode)
+func (a *App) handleLoaded(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte(a.StartTime.Format(time.RFC3339Nano)))
+}
+
+//reloadExtension checks if change in file needs a reload
+func reloadExtension(file string) bool {
+	switch filepath.Ext(file) {
+	case ".html", ".yml", ".yaml", ".json", ".sql", ".js", ".css":
+		return true
+	default:
+		return false
+	}
+}
+
+//watch reloads app when templates, config, api or translation files change (debug mode)
+func (a *App) watch() {
+	w := watcher.New()
+	var timer *time.Timer
+	go func() {
+		for {
+			select {
+			case event := <-w.Event:
+				if reloadExtension(event.Name()) {
+					log.Println("Change detected:", event.Path)
+					//wait for more changes (editors write multiple files)
+					if timer != nil {
+						timer.Stop()
+					}
+					timer = time.AfterFunc(200*time.Millisecond, func() {
+						a.Reload()
+					})
+				}
+			case err := <-w.Error:
+				log.Println("ERROR watching files:", err)
+			case <-w.Closed:
+				return
+			}
+		}
+	}()
+	var paths []string
+	if a.ComponentPaths != nil {
+		paths = a.ComponentPaths
+	} else {
+		paths = append(paths, a.ComponentsPath)
+	}
+	for _, path := range paths {
+		if err := w.AddRecursive(a.RootPath + path); err != nil {
+			log.Println("ERROR watching files:", err)
+		}
+	}
+	if err := w.Add(a.RootPath + a.ConfigFile); err != nil {
+		log.Println("ERROR watching files:", err)
+	}
+	if _, err := os.Stat(a.RootPath + a.MainCSSFile); err == nil {
+		//compiled css is part of stylesheet
+		if err := w.Add(a.RootPath + a.MainCSSFile); err != nil {
+			log.Println("ERROR watching files:", err)
+		}
+	}
+	log.Println("Watching components for changes...")
+	if err := w.Start(100 * time.Millisecond); err != nil {
+		log.Println("ERROR watching files:", err)
+	}
+}
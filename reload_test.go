@@ -0,0 +1,29 @@
+package components
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+//TestLoadFailureKeepsApp loads app with missing components path, app that is serving must not change
+func TestLoadFailureKeepsApp(t *testing.T) {
+	dir, err := ioutil.TempDir("", "components")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	router := NewRouter()
+	settings := &appSettings{defaultLocale: "en"}
+	rts := map[string]*Route{"items": {Route: "items", Type: "query"}}
+	a := &App{base: &App{RootPath: dir + "/", ComponentsPath: "missing"}, Router: router, settings: settings, routes: rts}
+	if _, err := a.load(); err == nil {
+		t.Fatal("expected error for missing components path")
+	}
+	if a.Router != router || a.settings != settings || len(a.routes) != 1 || a.routes["items"] == nil {
+		t.Error("app changed by failed load")
+	}
+	if a.base.routes != nil || a.base.Router != nil {
+		t.Error("base app changed by failed load")
+	}
+}
@@ -0,0 +1,174 @@
+package components
+
+import (
+	"context"
+	"html"
+	"net/http"
+	"sort"
+
+	"github.com/jmu0/dbAPI/db"
+	"github.com/jmu0/templates"
+)
+
+//RenderContext request scoped data for rendering a page.
+//cached templates are never modified, data is copied for every request
+type RenderContext struct {
+	Request     *http.Request
+	Args        map[string]string
+	Principal   *Principal
+	Locale      string
+	Params      map[string]string      //path parameters from page route
+	TypedParams map[string]interface{} //path parameters converted to type from page route, ex: {maat:int}
+	Title       string
+	Description string
+	Canonical   string
+	Meta        map[string]string
+	Content     string
+	Components  []string    //components in page, layout loads their script chunks
+	loader      *dataLoader //loads data of parts concurrently, nil: parts are rendered one by one
+}
+
+//NewRenderContext creates render context for request
+func NewRenderContext(r *http.Request) *RenderContext {
+	rc := &RenderContext{
+		Request: r,
+		Args:    GetRequestArgs(r),
+	}
+	rc.Locale = rc.Args["locale"]
+	if p, err := Authenticate(r); err == nil {
+		rc.Principal = p
+	}
+	return rc
+}
+
+//argsContext creates render context from request args, claims are taken from args.
+//principal is nil when args have no name claim (not authenticated)
+func argsContext(args map[string]string) *RenderContext {
+	rc := &RenderContext{
+		Args:   args,
+		Locale: args["locale"],
+	}
+	if args["name"] != "" {
+		rc.Principal = &Principal{Name: args["name"], Claims: args}
+	}
+	return rc
+}
+
+//SetParams sets path parameters, parameters are added to args
+func (rc *RenderContext) SetParams(params map[string]string) {
+	rc.Params = params
+	for k, v := range params {
+		rc.Args[k] = v
+	}
+}
+
+//Context returns context of request, cancelled when client disconnects or page timeout expires
+func (rc *RenderContext) Context() context.Context {
+	if rc.Request == nil {
+		return context.Background()
+	}
+	return rc.Request.Context()
+}
+
+//DataRequest returns request data for DataFuncCtx, with copy of args
+func (rc *RenderContext) DataRequest() *DataRequest {
+	args := make(map[string]string, len(rc.Args))
+	for k, v := range rc.Args {
+		args[k] = v
+	}
+	return &DataRequest{
+		Request:   rc.Request,
+		Principal: rc.Principal,
+		Args:      args,
+		Params:    rc.TypedParams,
+	}
+}
+
+//loadData gets data for component, with request loader when set
+func (rc *RenderContext) loadData(cmp *Component, conn db.Conn) ([]map[string]interface{}, error) {
+	if rc.loader == nil {
+		return cmp.GetDataCtx(rc.Context(), rc.DataRequest(), conn)
+	}
+	return rc.loader.load(rc.Context(), cmp, rc.DataRequest(), conn)
+}
+
+//Claims returns claims of authenticated principal
+func (rc *RenderContext) Claims() map[string]string {
+	if rc.Principal == nil {
+		return make(map[string]string)
+	}
+	return rc.Principal.Claims
+}
+
+//Data returns copy of base data with request data added
+func (rc *RenderContext) Data(base map[string]interface{}) map[string]interface{} {
+	data := make(map[string]interface{}, len(base)+4)
+	for k, v := range base {
+		data[k] = v
+	}
+	data["content"] = rc.Content
+	data["locale"] = rc.Locale
+	if rc.Title != "" {
+		data["title"] = rc.Title
+	}
+	if rc.Description != "" {
+		data["description"] = rc.Description
+	}
+	if rc.Canonical != "" {
+		data["canonical"] = rc.Canonical
+	}
+	data["meta"] = rc.metaTags()
+	for k, v := range rc.Params {
+		if _, ok := data[k]; !ok {
+			data[k] = v
+		}
+	}
+	if rc.Principal != nil {
+		data["user"] = rc.Principal.Name
+	} else {
+		data["user"] = ""
+	}
+	return data
+}
+
+//SetPage sets title, description, canonical and meta from page, translated to locale
+func (rc *RenderContext) SetPage(page Page, tm *templates.TemplateManager) {
+	if page.Title != "" {
+		rc.Title = Translate(tm, page.Title, rc.Locale)
+	}
+	if page.Description != "" {
+		rc.Description = Translate(tm, page.Description, rc.Locale)
+	}
+	rc.Canonical = page.Canonical
+	rc.Meta = make(map[string]string)
+	for name, content := range page.Meta {
+		rc.Meta[name] = Translate(tm, content, rc.Locale)
+	}
+}
+
+//metaTags returns html for description, canonical and meta tags
+func (rc *RenderContext) metaTags() string {
+	var ret string
+	if rc.Description != "" {
+		ret += "<meta name=\"description\" content=\"" + html.EscapeString(rc.Description) + "\">\n"
+	}
+	names := make([]string, 0, len(rc.Meta))
+	for name := range rc.Meta {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ret += "<meta name=\"" + html.EscapeString(name) + "\" content=\"" + html.EscapeString(rc.Meta[name]) + "\">\n"
+	}
+	if rc.Canonical != "" {
+		ret += "<link rel=\"canonical\" href=\"" + html.EscapeString(rc.Canonical) + "\">\n"
+	}
+	return ret
+}
+
+//render renders a copy of tmpl with data, tmpl is not modified
+func render(tm *templates.TemplateManager, tmpl *templates.Template, data map[string]interface{}, locale string) (string, error) {
+	t := *tmpl
+	t.Data = data
+	return tm.Render(&t, locale)
+}
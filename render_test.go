@@ -0,0 +1,125 @@
+package components
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jmu0/dbAPI/db"
+	"github.com/jmu0/templates"
+)
+
+//testComponent creates component with one template and data func returning id from path parameter
+func testComponent(name, html string, tm *templates.TemplateManager) Component {
+	c := Component{Name: name}
+	c.TemplateManager = templates.TemplateManager{Cache: map[string]*templates.Template{name: {HTML: html, Data: map[string]interface{}{}}}}
+	c.TemplateManager.LocalizationData = tm.LocalizationData
+	c.DataFuncCtx = func(ctx context.Context, req *DataRequest, conn db.Conn) ([]map[string]interface{}, error) {
+		return []map[string]interface{}{{"id": fmt.Sprint(req.Params["id"]), "user": req.Principal.Name}}, nil
+	}
+	return c
+}
+
+//testApp creates app with shared TemplateManager, page with nested parts rendered concurrently
+func testApp() (*App, Page) {
+	a := &App{DataWorkers: 4}
+	a.TemplateManager.LocalizationData = map[string]map[string]string{"title": {"en": "Title"}}
+	a.TemplateManager.Cache = map[string]*templates.Template{"main": {HTML: "<title>${{title}}</title><main>${{content}}</main>", Data: map[string]interface{}{"title": "app"}}}
+	a.Components = map[string]Component{
+		"outer": testComponent("outer", "<p>${{id}} ${{user}}</p>${{inner}}${{other}}", &a.TemplateManager),
+		"inner": testComponent("inner", "<i>${{id}}</i>", &a.TemplateManager),
+		"other": testComponent("other", "<b>${{user}}</b>", &a.TemplateManager),
+	}
+	page := Page{Route: "/item/{id}", Title: "title", Components: []Part{
+		{Name: "outer", Components: []Part{{Name: "inner"}, {Name: "other"}}},
+		{Name: "other"},
+	}}
+	return a, page
+}
+
+//TestRenderWithConcurrent renders page concurrently with shared app and TemplateManager (run with -race)
+func TestRenderWithConcurrent(t *testing.T) {
+	a, page := testApp()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprint(i)
+			user := "user" + id
+			r := WithPrincipal(httptest.NewRequest(http.MethodGet, "/item/"+id, nil), &Principal{Name: user, Claims: map[string]string{"name": user}})
+			rc := NewRenderContext(r)
+			rc.SetParams(map[string]string{"id": id})
+			rc.TypedParams = map[string]interface{}{"id": id}
+			rc.loader = newDataLoader(a.DataWorkers)
+			html, err := page.RenderWith(rc, a.Components, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			want := "<outer data-component='outer' rendered><p>" + id + " " + user + "</p><inner data-component='inner' rendered><i>" + id + "</i></inner><other data-component='other' rendered><b>" + user + "</b></other></outer><other data-component='other' rendered><b>" + user + "</b></other>"
+			if html != want {
+				t.Errorf("render %s:\n got %s\nwant %s", id, html, want)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+//TestServePageConcurrent serves page concurrently, response must only contain data of its own request (run with -race)
+func TestServePageConcurrent(t *testing.T) {
+	a, page := testApp()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprint(i)
+			user := "user" + id
+			r := WithPrincipal(httptest.NewRequest(http.MethodGet, "/item/"+id, nil), &Principal{Name: user, Claims: map[string]string{"name": user}})
+			w := httptest.NewRecorder()
+			a.servePage(w, r, page, map[string]string{"id": id}, map[string]interface{}{"id": id})
+			if w.Code != http.StatusOK {
+				t.Errorf("status %s: %d %s", id, w.Code, w.Body.String())
+				return
+			}
+			body := w.Body.String()
+			if !strings.Contains(body, "<p>"+id+" "+user+"</p>") || strings.Count(body, "<b>"+user+"</b>") != 2 {
+				t.Errorf("body %s: %s", id, body)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestArgsContext(t *testing.T) {
+	if rc := argsContext(map[string]string{"path": "/", "locale": "en"}); rc.Principal != nil || rc.Locale != "en" {
+		t.Errorf("principal without name claim: %v", rc.Principal)
+	}
+	if rc := argsContext(map[string]string{"name": "user", "klant": "k1"}); rc.Principal == nil || rc.Principal.Name != "user" || rc.Principal.Claims["klant"] != "k1" {
+		t.Errorf("principal with name claim: %v", rc.Principal)
+	}
+}
+
+//TestServePageTimeout serves page with data func exceeding deadline of page, must not be served with status 200
+func TestServePageTimeout(t *testing.T) {
+	a, page := testApp()
+	slow := testComponent("slow", "<p>${{id}}</p>", &a.TemplateManager)
+	slow.DataFuncCtx = func(ctx context.Context, req *DataRequest, conn db.Conn) ([]map[string]interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	a.Components["slow"] = slow
+	page.Timeout = "10ms"
+	page.Components = append(page.Components, Part{Name: "slow"})
+	r := WithPrincipal(httptest.NewRequest(http.MethodGet, "/item/1", nil), &Principal{Name: "user", Claims: map[string]string{"name": "user"}})
+	w := httptest.NewRecorder()
+	a.servePage(w, r, page, map[string]string{"id": "1"}, map[string]interface{}{"id": "1"})
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("status %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,527 @@
+package components
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jmu0/dbAPI/api"
+	"github.com/jmu0/dbAPI/db"
+)
+
+var andRegexp = regexp.MustCompile(`(?i)\s+and\s+`)
+var identRegexp = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+
+//responseRecorder records response of wrapped handler
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{header: make(http.Header)}
+}
+
+func (rr *responseRecorder) Header() http.Header {
+	return rr.header
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if rr.status == 0 {
+		rr.status = status
+	}
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	return rr.body.Write(b)
+}
+
+//writeTo copies recorded response to w
+func (rr *responseRecorder) writeTo(w http.ResponseWriter, body []byte) {
+	for k, v := range rr.header {
+		if k != "Content-Length" {
+			w.Header()[k] = v
+		}
+	}
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	w.WriteHeader(rr.status)
+	w.Write(body)
+}
+
+//restricted returns true when rest route has row filter or column lists
+func (r *Route) restricted() bool {
+	return r.Where != "" || len(r.ReadColumns) > 0 || len(r.ReadDeny) > 0 || len(r.WriteColumns) > 0 || len(r.WriteDeny) > 0
+}
+
+//whereConditions splits where in conditions
+func (r *Route) whereConditions() []string {
+	if strings.TrimSpace(r.Where) == "" {
+		return nil
+	}
+	return andRegexp.Split(strings.TrimSpace(r.Where), -1)
+}
+
+//rowPermitted checks row against where conditions
+func (r *Route) rowPermitted(row map[string]interface{}, claims map[string]string) (bool, error) {
+	values := make(map[string]string)
+	for k, v := range row {
+		values[k] = toString(v)
+	}
+	for _, cond := range r.whereConditions() {
+		ok, err := evalCondition(cond, values, claims)
+		if err != nil || ok == false {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+//filterRow removes columns that can't be read
+func (r *Route) filterRow(row map[string]interface{}) map[string]interface{} {
+	for col := range row {
+		if columnAllowed(col, r.ReadColumns, r.ReadDeny) == false {
+			delete(row, col)
+		}
+	}
+	return row
+}
+
+//filterRows applies row filter and read columns to rest response.
+//returns false when response is a single row that is not permitted
+func (r *Route) filterRows(body []byte, claims map[string]string) ([]byte, bool, error) {
+	var data interface{}
+	err := json.Unmarshal(body, &data)
+	if err != nil {
+		return nil, false, err
+	}
+	switch d := data.(type) {
+	case map[string]interface{}:
+		ok, err := r.rowPermitted(d, claims)
+		if err != nil || ok == false {
+			return nil, false, err
+		}
+		data = r.filterRow(d)
+	case []interface{}:
+		rows := make([]interface{}, 0)
+		for _, item := range d {
+			row, isRow := item.(map[string]interface{})
+			if isRow == false {
+				continue
+			}
+			ok, err := r.rowPermitted(row, claims)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				rows = append(rows, r.filterRow(row))
+			}
+		}
+		data = rows
+	}
+	body, err = json.Marshal(data)
+	return body, true, err
+}
+
+//whereSQL returns where clause with bind arguments for where conditions, ${claims.x} values are bound as arguments
+func (r *Route) whereSQL(claims map[string]string) (string, []interface{}, error) {
+	var conds []string
+	var args []interface{}
+	for _, cond := range r.whereConditions() {
+		name, op, value, err := parseCondition(cond)
+		if err != nil {
+			return "", nil, err
+		}
+		if !identRegexp.MatchString(name) {
+			return "", nil, errors.New("Invalid column in condition: " + cond)
+		}
+		value, ok := expand(value, claims)
+		if !ok {
+			//missing claim, no row matches (like rowPermitted)
+			return " where 1 = 0", nil, nil
+		}
+		switch op {
+		case "=", "==":
+			conds = append(conds, name+" = ?")
+			args = append(args, value)
+		case "!=":
+			conds = append(conds, name+" <> ?")
+			args = append(args, value)
+		case "in", "not in":
+			if !(strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]")) {
+				return "", nil, errors.New("Invalid list in condition: " + cond)
+			}
+			var list []string
+			for _, v := range strings.Split(value[1:len(value)-1], ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					list = append(list, "?")
+					args = append(args, v)
+				}
+			}
+			if len(list) == 0 {
+				if op == "in" {
+					conds = append(conds, "1 = 0")
+				}
+				continue
+			}
+			conds = append(conds, name+" "+op+" ("+strings.Join(list, ", ")+")")
+		}
+	}
+	if len(conds) == 0 {
+		return "", nil, nil
+	}
+	return " where " + strings.Join(conds, " and "), args, nil
+}
+
+//table returns schema.table of rest route
+func (r *Route) table() (string, error) {
+	spl := strings.Split(strings.Trim(r.Route, "/"), "/")
+	if len(spl) != 2 || !identRegexp.MatchString(spl[0]) || !identRegexp.MatchString(spl[1]) {
+		return "", errors.New("Invalid rest route: " + r.Route)
+	}
+	return spl[0] + "." + spl[1], nil
+}
+
+//keySQL returns select query for existing row with key of row as bind arguments.
+//returns empty query when row has no value for a key column (new row)
+func (r *Route) keySQL(row map[string]interface{}) (string, []interface{}, error) {
+	table, err := r.table()
+	if err != nil {
+		return "", nil, err
+	}
+	var conds []string
+	var args []interface{}
+	for _, col := range r.Key {
+		if !identRegexp.MatchString(col) {
+			return "", nil, errors.New("Invalid key column: " + col)
+		}
+		value, ok := row[col]
+		if !ok || value == nil {
+			return "", nil, nil
+		}
+		conds = append(conds, col+" = ?")
+		args = append(args, toString(value))
+	}
+	return "select * from " + table + " where " + strings.Join(conds, " and "), args, nil
+}
+
+//listSQL returns select query for rows of rest route with where conditions and limit / offset from query string as bind arguments.
+//other query string parameters are not supported on routes with where
+func (r *Route) listSQL(query url.Values, claims map[string]string) (string, []interface{}, error) {
+	table, err := r.table()
+	if err != nil {
+		return "", nil, err
+	}
+	where, args, err := r.whereSQL(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	sql := "select * from " + table + where
+	for key := range query {
+		if key != "limit" && key != "offset" {
+			return "", nil, &ParamError{Param: key, Msg: "not supported on route with where"}
+		}
+	}
+	for _, key := range []string{"limit", "offset"} {
+		if v := query.Get(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return "", nil, &ParamError{Param: key, Msg: "not a valid number"}
+			}
+			sql += " " + key + " ?"
+			args = append(args, n)
+		}
+	}
+	return sql, args, nil
+}
+
+//checkWrite checks columns in row against write columns and where conditions.
+//columns of where conditions and key are renamed to their declared case, sets columns with = condition when missing
+func (r *Route) checkWrite(row map[string]interface{}, claims map[string]string) (bool, string) {
+	for col := range row {
+		if columnAllowed(col, r.WriteColumns, r.WriteDeny) == false {
+			return false, "Column not allowed: " + col
+		}
+	}
+	names := append([]string{}, r.Key...)
+	for _, cond := range r.whereConditions() {
+		name, _, _, err := parseCondition(cond)
+		if err != nil {
+			return false, err.Error()
+		}
+		names = append(names, name)
+	}
+	if ok, msg := normalizeColumns(row, names); ok == false {
+		return false, msg
+	}
+	for _, cond := range r.whereConditions() {
+		name, op, value, _ := parseCondition(cond)
+		if _, ok := row[name]; !ok && (op == "=" || op == "==") {
+			if value, ok := expand(value, claims); ok {
+				row[name] = value
+			}
+		}
+	}
+	ok, err := r.rowPermitted(row, claims)
+	if err != nil || ok == false {
+		return false, "Row not allowed"
+	}
+	return true, ""
+}
+
+//normalizeColumns renames columns in row that differ only in case from names to the name,
+//lookups of where conditions are case sensitive. false when row has a column in more than one case
+func normalizeColumns(row map[string]interface{}, names []string) (bool, string) {
+	for _, name := range names {
+		for col, value := range row {
+			if col == name || !strings.EqualFold(col, name) {
+				continue
+			}
+			if _, ok := row[name]; ok {
+				return false, "Duplicate column: " + col
+			}
+			delete(row, col)
+			row[name] = value
+		}
+	}
+	return true, ""
+}
+
+//columnAllowed checks column against allow and deny lists
+func columnAllowed(col string, allow, deny []string) bool {
+	for _, c := range deny {
+		if strings.EqualFold(c, col) {
+			return false
+		}
+	}
+	if len(allow) == 0 {
+		return true
+	}
+	for _, c := range allow {
+		if strings.EqualFold(c, col) {
+			return true
+		}
+	}
+	return false
+}
+
+//toString converts json or db value to string for comparison
+func toString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		bytes, err := json.Marshal(val)
+		if err != nil {
+			return ""
+		}
+		return strings.Trim(string(bytes), "\"")
+	}
+}
+
+//restFiltered serves rest request with row filter and column lists applied.
+//writes to a row in the url are checked against the row filter before the write,
+//writes on the table check existing rows with the key of the rows in the body.
+func restFiltered(route Route, conn db.Conn, w http.ResponseWriter, r *http.Request) {
+	claims := GetClaims(r)
+	handler := api.RestHandler(apiURL, conn)
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) && route.Where != "" && restKey(route, r.URL.Path) == "" {
+		restList(route, conn, w, r, claims)
+		return
+	}
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		get := r.Clone(r.Context())
+		get.Method = http.MethodGet
+		rec := newResponseRecorder()
+		handler(rec, get)
+		if rec.status != http.StatusOK && rec.status != 0 {
+			rec.writeTo(w, rec.body.Bytes())
+			return
+		}
+		body, found, err := route.filterRows(rec.body.Bytes(), claims)
+		if err != nil {
+			log.Println("Error filtering rows:", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if found == false {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == http.MethodHead {
+			body = nil
+		}
+		rec.writeTo(w, body)
+		return
+	}
+
+	//check existing row
+	if restKey(route, r.URL.Path) != "" {
+		get := r.Clone(r.Context())
+		get.Method = http.MethodGet
+		get.Body = nil
+		get.ContentLength = 0
+		rec := newResponseRecorder()
+		handler(rec, get)
+		if rec.status == http.StatusOK || rec.status == 0 {
+			_, found, err := route.filterRows(rec.body.Bytes(), claims)
+			if err != nil || found == false {
+				http.NotFound(w, r)
+				return
+			}
+		}
+	}
+
+	if r.Method != http.MethodDelete && r.Body != nil {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+		var data interface{}
+		if len(body) > 0 {
+			err = json.Unmarshal(body, &data)
+			if err != nil {
+				http.Error(w, "Invalid json", http.StatusBadRequest)
+				return
+			}
+		}
+		var rows []map[string]interface{}
+		switch d := data.(type) {
+		case map[string]interface{}:
+			rows = append(rows, d)
+		case []interface{}:
+			for _, item := range d {
+				if row, ok := item.(map[string]interface{}); ok {
+					rows = append(rows, row)
+				} else {
+					http.Error(w, "Invalid json", http.StatusBadRequest)
+					return
+				}
+			}
+		}
+		for _, row := range rows {
+			if ok, msg := route.checkWrite(row, claims); ok == false {
+				log.Println("Forbidden:", r.Method, r.URL.Path, msg)
+				http.Error(w, msg, http.StatusForbidden)
+				return
+			}
+		}
+		if route.Where != "" && restKey(route, r.URL.Path) == "" {
+			//rows in body can overwrite existing rows
+			if status, msg := route.checkExisting(r, conn, rows, claims); status != 0 {
+				log.Println("Forbidden:", r.Method, r.URL.Path, msg)
+				http.Error(w, msg, status)
+				return
+			}
+		}
+		if data != nil {
+			body, err = json.Marshal(data)
+			if err != nil {
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+	}
+	handler(w, r)
+}
+
+//checkExisting checks existing rows with key of rows against row filter for write on table.
+//returns status and message when write is not allowed
+func (r *Route) checkExisting(req *http.Request, conn db.Conn, rows []map[string]interface{}, claims map[string]string) (int, string) {
+	if len(r.Key) == 0 {
+		return http.StatusForbidden, "Write on table needs key in route " + r.Route
+	}
+	if conn == nil {
+		log.Println("ERROR no database connection:", r.Route)
+		return http.StatusInternalServerError, "Internal server error"
+	}
+	for _, row := range rows {
+		query, args, err := r.keySQL(row)
+		if err != nil {
+			log.Println("Error in key:", r.Route, err)
+			return http.StatusInternalServerError, "Internal server error"
+		}
+		if query == "" {
+			continue
+		}
+		existing, err := queryArgs(req.Context(), conn, query, args)
+		if err != nil {
+			log.Println("Error getting row:", r.Route, err)
+			return http.StatusInternalServerError, "Internal server error"
+		}
+		for _, e := range existing {
+			if ok, err := r.rowPermitted(e, claims); err != nil || ok == false {
+				return http.StatusForbidden, "Row not allowed"
+			}
+		}
+	}
+	return 0, ""
+}
+
+//restKey returns part of url path after route, empty for requests on table
+func restKey(route Route, path string) string {
+	return strings.Trim(strings.TrimPrefix(path, apiURL+"/"+route.Route), "/")
+}
+
+//restList serves rows of table for rest route with row filter. where conditions, limit and offset are part of the query,
+//so only permitted rows are loaded and paging applies to permitted rows
+func restList(route Route, conn db.Conn, w http.ResponseWriter, r *http.Request, claims map[string]string) {
+	query, args, err := route.listSQL(r.URL.Query(), claims)
+	if err != nil {
+		if pe, ok := err.(*ParamError); ok {
+			http.Error(w, pe.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Println("Error in row filter:", route.Route, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if conn == nil {
+		log.Println("ERROR no database connection:", route.Route)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	rows, err := queryArgs(r.Context(), conn, query, args)
+	if err != nil {
+		log.Println("Error getting rows:", route.Route, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	for _, row := range rows {
+		route.filterRow(row)
+	}
+	body, err := json.Marshal(rows)
+	if err != nil {
+		log.Println("Error encoding rows:", route.Route, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(body)
+}
@@ -0,0 +1,112 @@
+package components
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestFilterRows(t *testing.T) {
+	route := Route{Route: "Assortiment/Maat", Where: "Klant = ${claims.klant}", ReadDeny: []string{"Inkoopprijs"}}
+	claims := map[string]string{"klant": "k1"}
+	body, found, err := route.filterRows([]byte(`[{"Maat":"1","Klant":"k1","Inkoopprijs":2},{"Maat":"2","Klant":"k2","Inkoopprijs":3},{"Maat":"3","Klant":"k1"}]`), claims)
+	if err != nil || found == false {
+		t.Fatal(found, err)
+	}
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(body, &rows); err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]interface{}{{"Maat": "1", "Klant": "k1"}, {"Maat": "3", "Klant": "k1"}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got %v, want %v", rows, want)
+	}
+
+	body, found, err = route.filterRows([]byte(`{"Maat":"1","Klant":"k1","Inkoopprijs":2}`), claims)
+	if err != nil || found == false || string(body) != `{"Klant":"k1","Maat":"1"}` {
+		t.Errorf("single row: %s %v %v", body, found, err)
+	}
+	_, found, err = route.filterRows([]byte(`{"Maat":"2","Klant":"k2"}`), claims)
+	if err != nil || found == true {
+		t.Errorf("row of other klant: %v %v", found, err)
+	}
+	_, found, _ = route.filterRows([]byte(`{"Maat":"1","Klant":"k1"}`), map[string]string{})
+	if found == true {
+		t.Error("row without claim")
+	}
+}
+
+func TestCheckWrite(t *testing.T) {
+	route := Route{Route: "Assortiment/Maat", Where: "Klant = ${claims.klant}", WriteColumns: []string{"Maat", "Omschrijving", "Klant"}}
+	claims := map[string]string{"klant": "k1"}
+	row := map[string]interface{}{"Maat": "1"}
+	if ok, msg := route.checkWrite(row, claims); ok == false {
+		t.Error(msg)
+	}
+	if row["Klant"] != "k1" {
+		t.Errorf("Klant not set from claims: %v", row)
+	}
+	if ok, _ := route.checkWrite(map[string]interface{}{"Maat": "1", "Klant": "k2"}, claims); ok == true {
+		t.Error("write to other klant allowed")
+	}
+	if ok, _ := route.checkWrite(map[string]interface{}{"Maat": "1", "Inkoopprijs": 2}, claims); ok == true {
+		t.Error("write to column not in write_columns allowed")
+	}
+	if ok, _ := route.checkWrite(map[string]interface{}{"Maat": "1"}, map[string]string{}); ok == true {
+		t.Error("write without claim allowed")
+	}
+	if ok, _ := route.checkWrite(map[string]interface{}{"Maat": "1", "klant": "k2"}, claims); ok == true {
+		t.Error("write to other klant with lower case column allowed")
+	}
+	row = map[string]interface{}{"Maat": "1", "KLANT": "k1"}
+	if ok, msg := route.checkWrite(row, claims); ok == false || row["Klant"] != "k1" || len(row) != 2 {
+		t.Errorf("column not normalized: %v %s", row, msg)
+	}
+	if ok, _ := route.checkWrite(map[string]interface{}{"Maat": "1", "Klant": "k1", "klant": "k2"}, claims); ok == true {
+		t.Error("duplicate column allowed")
+	}
+}
+
+func TestKeySQL(t *testing.T) {
+	route := Route{Route: "Assortiment/Maat", Where: "Klant = ${claims.klant}", Key: []string{"Maat", "Klant"}}
+	sql, args, err := route.keySQL(map[string]interface{}{"Maat": 1.0, "Klant": "k1"})
+	if err != nil || sql != "select * from Assortiment.Maat where Maat = ? and Klant = ?" || !reflect.DeepEqual(args, []interface{}{"1", "k1"}) {
+		t.Errorf("got %s %v %v", sql, args, err)
+	}
+	if sql, _, err := route.keySQL(map[string]interface{}{"Klant": "k1"}); sql != "" || err != nil {
+		t.Errorf("row without key: %s %v", sql, err)
+	}
+	route.Key = nil
+	if status, _ := route.checkExisting(nil, nil, nil, nil); status != http.StatusForbidden {
+		t.Errorf("write on table without key: %d", status)
+	}
+}
+
+func TestListSQL(t *testing.T) {
+	route := Route{Route: "Assortiment/Maat", Where: "Klant = ${claims.klant} and Status in [a, b] and Maat != 0"}
+	query, _ := url.ParseQuery("limit=10&offset=20")
+	sql, args, err := route.listSQL(query, map[string]string{"klant": "k1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "select * from Assortiment.Maat where Klant = ? and Status in (?, ?) and Maat <> ? limit ? offset ?"
+	if sql != want || !reflect.DeepEqual(args, []interface{}{"k1", "a", "b", "0", 10, 20}) {
+		t.Errorf("got %s %v", sql, args)
+	}
+	sql, args, err = route.listSQL(url.Values{}, map[string]string{})
+	if err != nil || sql != "select * from Assortiment.Maat where 1 = 0" || len(args) != 0 {
+		t.Errorf("without claim: %s %v %v", sql, args, err)
+	}
+	for _, q := range []string{"Naam=x", "limit=-1", "offset=a"} {
+		query, _ := url.ParseQuery(q)
+		if _, _, err := route.listSQL(query, map[string]string{"klant": "k1"}); err == nil {
+			t.Errorf("%s: expected error", q)
+		}
+	}
+	route.Where = "Klant; drop table x = 1"
+	if _, _, err := route.listSQL(url.Values{}, map[string]string{}); err == nil {
+		t.Error("expected error for invalid condition")
+	}
+}
@@ -0,0 +1,271 @@
+package components
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+var methodsGet = []string{http.MethodGet} //HEAD is allowed when GET is
+var methodsPost = []string{http.MethodPost}
+var methodsQuery = []string{http.MethodGet, http.MethodPost}
+
+//RouteInfo route in router table
+type RouteInfo struct {
+	Pattern   string       `json:"pattern"`             //path, pattern ending with / matches all paths below
+	Methods   []string     `json:"methods"`             //allowed methods, empty: all methods
+	Type      string       `json:"type"`                //page, component, template, script, static, auth, locale, api, debug
+	Auth      bool         `json:"auth"`                //authentication required
+	Roles     []string     `json:"roles,omitempty"`     //user needs one of roles
+	Component string       `json:"component,omitempty"` //component route came from
+	Source    string       `json:"source,omitempty"`    //file route came from: app.yml, api.yml, template, script
+	Routes    []RouteInfo  `json:"routes,omitempty"`    //pages with path parameters matched by pattern
+	Handler   http.Handler `json:"-"`
+}
+
+//Router routes requests by path and method. routes can be added, replaced and removed while serving
+type Router struct {
+	routes []RouteInfo //sorted by pattern length, longest first
+	mutex  sync.RWMutex
+}
+
+//NewRouter creates empty router
+func NewRouter() *Router {
+	return &Router{routes: make([]RouteInfo, 0)}
+}
+
+//Handle adds route, replaces route with same pattern and methods
+func (rt *Router) Handle(pattern string, methods []string, typ string, h http.Handler) {
+	rt.HandleRoute(RouteInfo{Pattern: pattern, Methods: methods, Type: typ, Handler: h})
+}
+
+//HandleRoute adds route with info for route table, replaces route with same pattern and methods
+func (rt *Router) HandleRoute(route RouteInfo) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	route.Methods = normalizeMethods(route.Methods)
+	pattern := route.Pattern
+	for i := range rt.routes {
+		if rt.routes[i].Pattern == pattern && sameMethods(rt.routes[i].Methods, route.Methods) {
+			rt.routes[i] = route
+			return
+		}
+	}
+	rt.routes = append(rt.routes, route)
+	sort.SliceStable(rt.routes, func(i, j int) bool {
+		return len(rt.routes[i].Pattern) > len(rt.routes[j].Pattern)
+	})
+}
+
+//HandleFunc adds route for handler func
+func (rt *Router) HandleFunc(pattern string, methods []string, typ string, h func(w http.ResponseWriter, r *http.Request)) {
+	rt.Handle(pattern, methods, typ, http.HandlerFunc(h))
+}
+
+//Remove removes all routes for pattern
+func (rt *Router) Remove(pattern string) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	routes := make([]RouteInfo, 0, len(rt.routes))
+	for _, route := range rt.routes {
+		if route.Pattern != pattern {
+			routes = append(routes, route)
+		}
+	}
+	rt.routes = routes
+}
+
+//Routes returns route table sorted by pattern
+func (rt *Router) Routes() []RouteInfo {
+	rt.mutex.RLock()
+	defer rt.mutex.RUnlock()
+	routes := make([]RouteInfo, len(rt.routes))
+	copy(routes, rt.routes)
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].Pattern < routes[j].Pattern
+	})
+	return routes
+}
+
+//ServeHTTP serves request with longest matching pattern that allows method.
+//like http.ServeMux paths are cleaned and /path is redirected to /path/ if only /path/ exists.
+//405 when path matches but method is not allowed
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if p := cleanPath(r.URL.Path); p != r.URL.Path && r.Method != http.MethodConnect {
+		u := *r.URL
+		u.Path = p
+		http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+		return
+	}
+	route, allow, redirect := rt.match(r.URL.Path, r.Method)
+	if route != nil {
+		route.Handler.ServeHTTP(w, r)
+		return
+	}
+	if redirect {
+		u := *r.URL
+		u.Path += "/"
+		http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+		return
+	}
+	if len(allow) > 0 {
+		w.Header().Set("Allow", strings.Join(allow, ", "))
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+//match returns route for path and method, allowed methods if path matches but method doesn't,
+//redirect when path + "/" has an exact route
+func (rt *Router) match(p, method string) (*RouteInfo, []string, bool) {
+	rt.mutex.RLock()
+	defer rt.mutex.RUnlock()
+	var allow []string
+	var redirect bool
+	for i := range rt.routes {
+		route := rt.routes[i]
+		if route.Pattern == p+"/" {
+			redirect = true
+		}
+		if route.Pattern != p && !(strings.HasSuffix(route.Pattern, "/") && strings.HasPrefix(p, route.Pattern)) {
+			continue
+		}
+		if route.allows(method) {
+			if redirect && route.Pattern != p {
+				//ServeMux: /path redirects to /path/ before matching shorter patterns
+				return nil, nil, true
+			}
+			return &route, nil, false
+		}
+		if allow == nil {
+			allow = route.Methods
+		}
+	}
+	return nil, allow, redirect
+}
+
+//allows checks if method is allowed for route, HEAD is allowed when GET is
+func (route *RouteInfo) allows(method string) bool {
+	if len(route.Methods) == 0 {
+		return true
+	}
+	for _, m := range route.Methods {
+		if m == method || (m == http.MethodGet && method == http.MethodHead) {
+			return true
+		}
+	}
+	return false
+}
+
+//normalizeMethods returns upper case methods
+func normalizeMethods(methods []string) []string {
+	var ret []string
+	for _, m := range methods {
+		if m = strings.ToUpper(strings.TrimSpace(m)); m != "" {
+			ret = append(ret, m)
+		}
+	}
+	return ret
+}
+
+//sameMethods checks if methods are equal, order doesn't matter
+func sameMethods(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, m := range a {
+		found := false
+		for _, n := range b {
+			if m == n {
+				found = true
+			}
+		}
+		if found == false {
+			return false
+		}
+	}
+	return true
+}
+
+//parseMethods parses comma or space separated methods, ex: get, post
+func parseMethods(s string) []string {
+	return normalizeMethods(strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == ';' || r == '|'
+	}))
+}
+
+//cleanPath returns canonical path, like http.ServeMux
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	np := path.Clean(p)
+	if p[len(p)-1] == '/' && np != "/" {
+		np += "/"
+	}
+	return np
+}
+
+//Routes returns route table of app
+func (a *App) Routes() []RouteInfo {
+	handlerMutex.RLock()
+	rt := a.Router
+	handlerMutex.RUnlock()
+	if rt == nil {
+		return make([]RouteInfo, 0)
+	}
+	return rt.Routes()
+}
+
+//WriteRoutes writes route table as text
+func WriteRoutes(w io.Writer, routes []RouteInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tMETHODS\tPATTERN\tAUTH\tCOMPONENT\tSOURCE")
+	var write func(routes []RouteInfo, indent string)
+	write = func(routes []RouteInfo, indent string) {
+		for _, route := range routes {
+			methods := strings.Join(route.Methods, ",")
+			if methods == "" {
+				methods = "*"
+			}
+			auth := ""
+			if route.Auth {
+				auth = "yes"
+				if len(route.Roles) > 0 {
+					auth += " (" + strings.Join(route.Roles, ",") + ")"
+				}
+			}
+			fmt.Fprintln(tw, route.Type+"\t"+methods+"\t"+indent+route.Pattern+"\t"+auth+"\t"+route.Component+"\t"+route.Source)
+			write(route.Routes, indent+"  ")
+		}
+	}
+	write(routes, "")
+	return tw.Flush()
+}
+
+//handleRoutes serves route table as text, or json with ?format=json (debug mode)
+func (a *App) handleRoutes(w http.ResponseWriter, r *http.Request) {
+	routes := a.Router.Routes()
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		bytes, err := json.Marshal(routes)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(bytes)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	WriteRoutes(w, routes)
+}
@@ -0,0 +1,67 @@
+package components
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	rt := NewRouter()
+	handler := func(name string) func(w http.ResponseWriter, r *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name))
+		}
+	}
+	rt.HandleFunc("/", nil, "page", handler("root"))
+	rt.HandleFunc("/plant/", methodsGet, "page", handler("plant"))
+	rt.HandleFunc("/auth/login", methodsPost, "auth", handler("login"))
+	rt.HandleFunc("/api/items/", []string{"get", "post"}, "api", handler("items"))
+	tests := []struct {
+		method   string
+		path     string
+		body     string
+		code     int
+		location string
+	}{
+		{http.MethodGet, "/", "root", http.StatusOK, ""},
+		{http.MethodGet, "/plant/abc", "plant", http.StatusOK, ""},
+		{http.MethodHead, "/plant/", "plant", http.StatusOK, ""},
+		{http.MethodGet, "/other", "root", http.StatusOK, ""},
+		{http.MethodPost, "/auth/login", "login", http.StatusOK, ""},
+		{http.MethodPost, "/api/items/1", "items", http.StatusOK, ""},
+		{http.MethodGet, "/plant", "", http.StatusMovedPermanently, "/plant/"},
+		{http.MethodGet, "/plant/../api/items/", "", http.StatusMovedPermanently, "/api/items/"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+		if w.Code != test.code || (test.code == http.StatusOK && w.Body.String() != test.body) || w.Header().Get("Location") != test.location {
+			t.Errorf("%s %s: %d %s %s", test.method, test.path, w.Code, w.Body.String(), w.Header().Get("Location"))
+		}
+	}
+}
+
+func TestRouterMethodNotAllowed(t *testing.T) {
+	rt := NewRouter()
+	rt.HandleFunc("/auth/login", methodsPost, "auth", func(w http.ResponseWriter, r *http.Request) {})
+	rt.HandleFunc("/api/items/", []string{"get", "post"}, "api", func(w http.ResponseWriter, r *http.Request) {})
+	for path, allow := range map[string]string{"/auth/login": "POST", "/api/items/1": "GET, POST"} {
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, path, nil))
+		if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != allow {
+			t.Errorf("%s: %d allow %q", path, w.Code, w.Header().Get("Allow"))
+		}
+	}
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unknown", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown path: %d", w.Code)
+	}
+	rt.Remove("/auth/login")
+	w = httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/login", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("removed route: %d", w.Code)
+	}
+}
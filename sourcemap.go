@@ -0,0 +1,181 @@
+package components
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+const base64VLQ = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+//sourceMap builds source map (v3) for bundle of minified files. minify only removes whitespace and comments,
+//positions in minified files are found by matching them with the original file
+type sourceMap struct {
+	sources  []string
+	contents []string
+	mappings bytes.Buffer
+	col      int    //generated column
+	segments int    //segments in generated line
+	prev     [4]int //previous segment: generated column, source, source line, source column
+}
+
+//sourceMapJSON source map file
+type sourceMapJSON struct {
+	Version        int      `json:"version"`
+	File           string   `json:"file"`
+	SourceRoot     string   `json:"sourceRoot"`
+	Sources        []string `json:"sources"`
+	SourcesContent []string `json:"sourcesContent"`
+	Names          []string `json:"names"`
+	Mappings       string   `json:"mappings"`
+}
+
+//add adds minified file to map, minified is appended to bundle after previous files
+func (sm *sourceMap) add(source string, original, minified []byte) {
+	src := len(sm.sources)
+	sm.sources = append(sm.sources, source)
+	sm.contents = append(sm.contents, string(original))
+	lines := []int{0}
+	for i, c := range original {
+		if c == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	o := 0
+	gap := true
+	for _, c := range minified {
+		if c == '\n' {
+			sm.newLine()
+			if o < len(original) && original[o] == '\n' {
+				o++
+			}
+			gap = true
+			continue
+		}
+		if o < len(original) && original[o] == c {
+			if gap {
+				sm.segment(src, lines, o)
+				gap = false
+			}
+			o++
+			sm.col++
+			continue
+		}
+		p := skipSpace(original, o)
+		end := p + 16
+		if end > len(original) {
+			end = len(original)
+		}
+		if p < end {
+			if k := bytes.IndexByte(original[p:end], c); k == 0 || (k > 0 && c != ';') {
+				//continue after removed whitespace and comments, or changed token
+				o = p + k
+				sm.segment(src, lines, o)
+				gap = false
+				o++
+				sm.col++
+				continue
+			}
+		}
+		//added by minify, ex: ; for new line
+		sm.col++
+		gap = true
+	}
+}
+
+//segment adds mapping from generated column to offset in source
+func (sm *sourceMap) segment(src int, lines []int, offset int) {
+	line := sort.Search(len(lines), func(i int) bool { return lines[i] > offset }) - 1
+	fields := [4]int{sm.col, src, line, offset - lines[line]}
+	if sm.segments > 0 {
+		sm.mappings.WriteByte(',')
+	}
+	for i := range fields {
+		writeVLQ(&sm.mappings, fields[i]-sm.prev[i])
+	}
+	sm.prev = fields
+	sm.segments++
+}
+
+//newLine starts generated line, generated column is relative to line start
+func (sm *sourceMap) newLine() {
+	sm.mappings.WriteByte(';')
+	sm.col = 0
+	sm.segments = 0
+	sm.prev[0] = 0
+}
+
+//JSON returns source map for bundle file
+func (sm *sourceMap) JSON(file string) ([]byte, error) {
+	return json.Marshal(sourceMapJSON{
+		Version:        3,
+		File:           file,
+		SourceRoot:     "/",
+		Sources:        sm.sources,
+		SourcesContent: sm.contents,
+		Names:          []string{},
+		Mappings:       sm.mappings.String(),
+	})
+}
+
+//writeVLQ writes base64 vlq encoded value
+func writeVLQ(buf *bytes.Buffer, v int) {
+	vlq := v << 1
+	if v < 0 {
+		vlq = (-v << 1) | 1
+	}
+	for {
+		digit := vlq & 31
+		vlq >>= 5
+		if vlq > 0 {
+			digit |= 32
+		}
+		buf.WriteByte(base64VLQ[digit])
+		if vlq == 0 {
+			return
+		}
+	}
+}
+
+//skipSpace returns offset after whitespace and comments in js source
+func skipSpace(src []byte, o int) int {
+	for o < len(src) {
+		switch {
+		case src[o] == ' ' || src[o] == '\t' || src[o] == '\n' || src[o] == '\r':
+			o++
+		case bytes.HasPrefix(src[o:], []byte("//")):
+			if i := bytes.IndexByte(src[o:], '\n'); i >= 0 {
+				o += i + 1
+			} else {
+				o = len(src)
+			}
+		case bytes.HasPrefix(src[o:], []byte("/*")):
+			if i := bytes.Index(src[o+2:], []byte("*/")); i >= 0 {
+				o += i + 4
+			} else {
+				o = len(src)
+			}
+		default:
+			return o
+		}
+	}
+	return o
+}
+
+//handleSourceMap serves source map of script bundle, only to permitted users when source_map_auth or source_map_roles is set
+func (a *App) handleSourceMap(smap []byte) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r = authenticateRequest(r)
+		if authorize(w, r, a.SourceMapAuth, a.SourceMapRoles, nil) == false {
+			return
+		}
+		if a.SourceMapAuth == true || len(a.SourceMapRoles) > 0 {
+			w.Header().Set("Cache-Control", "private, max-age=90")
+			addVary(w, "Authorization", "Cookie")
+		} else {
+			w.Header().Set("Cache-control", "max-age=90")
+		}
+		writeResponse(w, r, "application/json; charset=utf-8", smap, "", a.StartTime)
+	}
+}
@@ -0,0 +1,99 @@
+package components
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteVLQ(t *testing.T) {
+	tests := map[int]string{0: "A", 1: "C", -1: "D", 15: "e", -15: "f", 16: "gB", 123: "2H", -123: "3H", 1000: "w+B"}
+	for v, want := range tests {
+		var buf bytes.Buffer
+		writeVLQ(&buf, v)
+		if buf.String() != want {
+			t.Errorf("%d: got %s, want %s", v, buf.String(), want)
+		}
+	}
+}
+
+//decodeMappings decodes mappings to generated line, column => source, line, column
+func decodeMappings(t *testing.T, mappings string) map[[2]int][3]int {
+	ret := make(map[[2]int][3]int)
+	var src, line, col int
+	for l, group := range strings.Split(mappings, ";") {
+		gen := 0
+		if group == "" {
+			continue
+		}
+		for _, segment := range strings.Split(group, ",") {
+			var fields []int
+			v, shift := 0, uint(0)
+			for _, c := range segment {
+				digit := strings.IndexRune(base64VLQ, c)
+				v |= (digit & 31) << shift
+				shift += 5
+				if digit&32 == 0 {
+					if v&1 == 1 {
+						fields = append(fields, -(v >> 1))
+					} else {
+						fields = append(fields, v>>1)
+					}
+					v, shift = 0, 0
+				}
+			}
+			if len(fields) != 4 {
+				t.Fatalf("segment %s: %v", segment, fields)
+			}
+			gen += fields[0]
+			src += fields[1]
+			line += fields[2]
+			col += fields[3]
+			ret[[2]int{l, gen}] = [3]int{src, line, col}
+		}
+	}
+	return ret
+}
+
+func TestSourceMapAdd(t *testing.T) {
+	first := []byte("var a = 1;\n\n//comment\nfunction f(x) {\n    return x;\n}\n")
+	firstMin := []byte("var a=1;function f(x){return x;}")
+	second := []byte("/* c */\nm.x = 2;\n")
+	secondMin := []byte("m.x=2;")
+	sm := &sourceMap{}
+	sm.add("first.js", first, firstMin)
+	sm.newLine()
+	sm.add("second.js", second, secondMin)
+	mappings := decodeMappings(t, sm.mappings.String())
+	tests := []struct {
+		gen [2]int //generated line, column
+		src [3]int //source, line, column
+	}{
+		{[2]int{0, 0}, [3]int{0, 0, 0}},  //var
+		{[2]int{0, 8}, [3]int{0, 3, 0}},  //function
+		{[2]int{0, 22}, [3]int{0, 4, 4}}, //return
+		{[2]int{1, 0}, [3]int{1, 1, 0}},  //m.x
+	}
+	for _, test := range tests {
+		src, ok := mappings[test.gen]
+		if !ok {
+			t.Errorf("no mapping for %v in %s", test.gen, sm.mappings.String())
+			continue
+		}
+		if src != test.src {
+			t.Errorf("%v: got %v, want %v", test.gen, src, test.src)
+		}
+	}
+	smap, err := sm.JSON("app.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed sourceMapJSON
+	if err := json.Unmarshal(smap, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Version != 3 || len(parsed.Sources) != 2 || parsed.SourcesContent[1] != string(second) {
+		t.Errorf("source map: %s", smap)
+	}
+}
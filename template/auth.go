@@ -3,9 +3,6 @@ package main
 import (
 	"errors"
 	"log"
-	"net/http"
-
-	"git.muysers.nl/jmu0/jwt"
 )
 
 func authenticate(username, password string) (map[string]string, error) {
@@ -21,10 +18,3 @@ func authenticate(username, password string) (map[string]string, error) {
 	log.Println("Authenticated:", ret["name"])
 	return ret, nil
 }
-
-func handleAuth(w http.ResponseWriter, r *http.Request) {
-	err := jwt.HandleAuth(w, r, authenticate)
-	if err != nil {
-		log.Println("Auth failed:", err)
-	}
-}
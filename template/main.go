@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"log"
 	"net/http"
 
@@ -20,22 +22,35 @@ var mx *http.ServeMux
 
 func main() {
 	s := map[string]string{
-		"root":   "./",
-		"static": "static",
+		"root":        "./",
+		"static":      "static",
+		"auth_secret": "",
 	}
 	settings.Load("config.yml", &s)
+	secret := []byte(s["auth_secret"])
+	if len(secret) == 0 {
+		//tokens are invalid after restart, set auth_secret in config.yml
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("No auth_secret in config.yml, using random secret")
+	}
 	conn, err = api.GetConnection("config.yml")
 	mx = http.NewServeMux()
 
 	var app = components.App{
-		ConfigFile: "app.yml",
-		Mux:        mx,
-		RootPath:   s["root"],
-		StaticPath: s["static"],
-		Conn:       conn,
-		DataFuncs:  make(map[string]components.DataFunc),
+		ConfigFile:   "app.yml",
+		Mux:          mx,
+		RootPath:     s["root"],
+		StaticPath:   s["static"],
+		Conn:         conn,
+		DataFuncs:    make(map[string]components.DataFunc),
+		DataFuncsCtx: make(map[string]components.DataFuncCtx),
+		AuthFunc:     authenticate,
+		AuthSecret:   secret,
 	}
-	app.DataFuncs["example"] = getExampleData
+	app.DataFuncsCtx["example"] = getExampleData
 
 	err := app.Init()
 	if err != nil {
@@ -46,15 +61,18 @@ func main() {
 	log.Fatal(http.ListenAndServe(app.Port, mx))
 }
 
-func getExampleData(keys []string, conn db.Conn) ([]map[string]interface{}, error) {
-	log.Println("DEBUG getExampleData:", keys)
+func getExampleData(ctx context.Context, req *components.DataRequest, conn db.Conn) ([]map[string]interface{}, error) {
+	log.Println("DEBUG getExampleData:", req.Keys, req.Params)
 	var ret = make([]map[string]interface{}, 0)
 	var one = make(map[string]interface{})
-	if len(keys) > 0 {
-		one["testkey"] = keys[0]
+	if len(req.Keys) > 0 {
+		one["testkey"] = req.Keys[0]
+	}
+	if len(req.Keys) > 1 {
+		one["namekey"] = req.Keys[1]
 	}
-	if len(keys) > 1 {
-		one["namekey"] = keys[1]
+	if maat, ok := req.Params["maat"].(int64); ok {
+		one["namekey"] = maat
 	}
 	one["idkey"] = "example from getExampleData func."
 	ret = append(ret, one)